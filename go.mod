@@ -0,0 +1,3 @@
+module github.com/PramanandaSarkar/utility
+
+go 1.24
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// aliases maps the shortcuts defined with the alias command to the
+// commands they stand for.
+var aliases = map[string]string{}
+
+func aliasesPath() string {
+	return filepath.Join(configDir(), "aliases.json")
+}
+
+// loadAliases reads the aliases saved by saveAliases. A missing file
+// defines none.
+func loadAliases(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, &aliases)
+}
+
+// saveAliases writes the aliases to path so later sessions have them.
+func saveAliases(path string) error {
+	data, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// expandAlias replaces a leading alias in a command with its expansion,
+// keeping the words after it. Expansions are not expanded again, so
+// aliases cannot loop.
+func expandAlias(fields []string) ([]string, error) {
+	expansion, ok := aliases[strings.ToLower(fields[0])]
+	if !ok {
+		return fields, nil
+	}
+	expanded, err := splitCommand(expansion)
+	if err != nil {
+		return nil, fmt.Errorf("alias %s: %w", fields[0], err)
+	}
+	if len(expanded) == 0 {
+		return nil, fmt.Errorf("alias %s is empty", fields[0])
+	}
+	return append(expanded, fields[1:]...), nil
+}
+
+// joinCommand is the inverse of splitCommand: it joins words into a
+// line, quoting those that would otherwise be split or changed.
+func joinCommand(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if word == "" || strings.ContainsAny(word, " \t\"'\\") {
+			word = "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+		}
+		quoted[i] = word
+	}
+	return strings.Join(quoted, " ")
+}
+
+func defineAlias(args []string) error {
+	name := strings.ToLower(args[0])
+	if lookupCommand(name) != nil {
+		return fmt.Errorf("cannot alias %s: it is already a command", name)
+	}
+	expansion := args[1]
+	if len(args) > 2 {
+		expansion = joinCommand(args[1:])
+	}
+	if _, err := splitCommand(expansion); err != nil {
+		return fmt.Errorf("cannot alias %s: %w", name, err)
+	}
+
+	aliases[name] = expansion
+	if err := saveAliases(aliasesPath()); err != nil {
+		return fmt.Errorf("alias %s set for this session only: cannot save it: %w", name, err)
+	}
+	fmt.Printf("Alias %s = %s\n", name, expansion)
+	return nil
+}
+
+func removeAlias(args []string) error {
+	name := strings.ToLower(args[0])
+	if _, ok := aliases[name]; !ok {
+		return fmt.Errorf("no alias %s", name)
+	}
+
+	delete(aliases, name)
+	if err := saveAliases(aliasesPath()); err != nil {
+		return fmt.Errorf("alias %s removed for this session only: cannot save it: %w", name, err)
+	}
+	fmt.Printf("Removed alias %s\n", name)
+	return nil
+}
+
+func listAliases() {
+	if len(aliases) == 0 {
+		fmt.Println("No aliases")
+		return
+	}
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Alias\tCommand")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, aliases[name])
+	}
+	w.Flush()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// BackupHistory uploads a copy of the history file to key in the S3
+// bucket, gzipped first if compress is set. It returns the number of
+// bytes uploaded and the object's ETag.
+func (t *Timer) BackupHistory(bucket, key string, compress bool) (int, string, error) {
+	t.historyMu.Lock()
+	data, err := os.ReadFile(t.Config.HistoryFile)
+	t.historyMu.Unlock()
+	if err != nil {
+		return 0, "", err
+	}
+
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return 0, "", err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, "", err
+		}
+		data = buf.Bytes()
+	}
+
+	etag, err := putS3Object(bucket, key, data)
+	if err != nil {
+		return 0, "", err
+	}
+	return len(data), etag, nil
+}
+
+// putS3Object stores data as bucket/key with the aws CLI, which takes
+// its credentials from the environment, and returns the ETag.
+func putS3Object(bucket, key string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp("", "timer-backup-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("aws", "s3api", "put-object",
+		"--bucket", bucket, "--key", key, "--body", tmp.Name(), "--output", "json")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("backup-history needs the aws CLI on PATH")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	var result struct {
+		ETag string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return "", fmt.Errorf("reading aws output: %w", err)
+	}
+	return strings.Trim(result.ETag, `"`), nil
+}
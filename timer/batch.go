@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// isTerminal reports whether f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// batchCommand turns a piped line into a command. As in a task file, the
+// leading "add" may be left out, so `echo "Study 25m" | timer` works.
+func batchCommand(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || lookupCommand(fields[0]) != nil || aliases[strings.ToLower(fields[0])] != "" {
+		return line
+	}
+	return "add " + line
+}
+
+// waitUntilIdle returns once the queue is empty and nothing is running,
+// after whatever the timer does at the end of a session.
+func waitUntilIdle(t *Timer) {
+	events := make(chan Event, 16)
+	t.Subscribe(events)
+	defer t.Unsubscribe(events)
+
+	if t.IdleFor() > 0 {
+		return
+	}
+	for event := range events {
+		if event.Type == EventQueueEmpty {
+			return
+		}
+	}
+}
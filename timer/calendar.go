@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// calendarCellWidth is the width of a day's column in the calendar.
+const calendarCellWidth = 7
+
+// calendarCommand handles "timer calendar [--week|--month] [--target
+// <duration>]", showing the time recorded on each day of the current
+// week, the default, or month.
+func calendarCommand(t *Timer, args []string) error {
+	month, args := takeBoolOption(args, "month")
+	_, args = takeBoolOption(args, "week")
+	targetValue, args, _, err := takeOption(args, "target")
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("usage: timer calendar [--week|--month] [--target <duration>]")
+	}
+	var target time.Duration
+	if targetValue != "" {
+		if target, err = parseDuration(targetValue); err != nil {
+			return fmt.Errorf("invalid --target: %w", err)
+		}
+	}
+
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	if month {
+		showCalendarMonth(os.Stdout, entries, time.Now(), target)
+	} else {
+		showCalendarWeek(os.Stdout, entries, time.Now(), target)
+	}
+	return nil
+}
+
+// dailyTotals adds up the time recorded on each day, by date.
+func dailyTotals(entries []HistoryEntry) map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for _, e := range entries {
+		totals[e.CompletedAt.Local().Format(time.DateOnly)] += e.Duration
+	}
+	return totals
+}
+
+// startOfWeek returns midnight on the Monday of the week holding day.
+func startOfWeek(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, time.Local)
+}
+
+func showCalendarWeek(w io.Writer, entries []HistoryEntry, now time.Time, target time.Duration) {
+	totals := dailyTotals(entries)
+	monday := startOfWeek(now)
+	fmt.Fprintf(w, "Week of %s\n", monday.Format("Mon 2 Jan 2006"))
+	writeCalendarHeader(w)
+
+	var total time.Duration
+	var cells []string
+	for i := 0; i < 7; i++ {
+		day := monday.AddDate(0, 0, i)
+		d := totals[day.Format(time.DateOnly)]
+		total += d
+		cells = append(cells, calendarCell(calendarDuration(d), d, day, now, target))
+	}
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, ""), " "))
+	fmt.Fprintf(w, "Total: %s\n", calendarDuration(total))
+}
+
+// showCalendarMonth shows the month as weeks of day numbers, each with a
+// line of totals below it.
+func showCalendarMonth(w io.Writer, entries []HistoryEntry, now time.Time, target time.Duration) {
+	totals := dailyTotals(entries)
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	fmt.Fprintln(w, first.Format("January 2006"))
+	writeCalendarHeader(w)
+
+	var total time.Duration
+	for monday := startOfWeek(first); monday.Month() == now.Month() || monday.Before(first); monday = monday.AddDate(0, 0, 7) {
+		var numbers, cells []string
+		for i := 0; i < 7; i++ {
+			day := monday.AddDate(0, 0, i)
+			if day.Month() != now.Month() {
+				numbers = append(numbers, strings.Repeat(" ", calendarCellWidth))
+				cells = append(cells, strings.Repeat(" ", calendarCellWidth))
+				continue
+			}
+			d := totals[day.Format(time.DateOnly)]
+			total += d
+			numbers = append(numbers, calendarCell(fmt.Sprint(day.Day()), d, day, now, target))
+			cells = append(cells, calendarCell(calendarDuration(d), d, day, now, target))
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(numbers, ""), " "))
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, ""), " "))
+	}
+	fmt.Fprintf(w, "Total: %s\n", calendarDuration(total))
+}
+
+func writeCalendarHeader(w io.Writer) {
+	var header strings.Builder
+	for _, name := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		fmt.Fprintf(&header, "%-*s", calendarCellWidth, name)
+	}
+	fmt.Fprintln(w, strings.TrimRight(header.String(), " "))
+}
+
+// calendarCell pads text to a column, drawing it in the completed colour
+// when the day's total d reaches target and in reverse video on today.
+func calendarCell(text string, d time.Duration, day, now time.Time, target time.Duration) string {
+	var styles []string
+	if target > 0 && d >= target {
+		styles = append(styles, colors.Completed)
+	}
+	if day.Format(time.DateOnly) == now.Format(time.DateOnly) {
+		styles = append(styles, "7")
+	}
+	if len(styles) == 0 {
+		return fmt.Sprintf("%-*s", calendarCellWidth, text)
+	}
+	// The padding stays outside the escape codes so the columns line up.
+	padding := strings.Repeat(" ", max(calendarCellWidth-len(text), 1))
+	return colorize(strings.Join(styles, ";"), text) + padding
+}
+
+// calendarDuration writes d to the minute in a few characters: 2h, 1h30,
+// 45m, or 0 for nothing.
+func calendarDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours, minutes := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case d == 0:
+		return "0"
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%02d", hours, minutes)
+}
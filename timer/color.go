@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// colorEnabled is turned off by --no-color or the NO_COLOR environment
+// variable (https://no-color.org).
+var colorEnabled = os.Getenv("NO_COLOR") == ""
+
+// ColorScheme maps what a message is about to the ANSI SGR parameters it
+// is drawn with, such as "32" for green or "38;5;208" for a 256-colour
+// orange.
+type ColorScheme struct {
+	// Running is a countdown with plenty of time left.
+	Running string
+	Paused  string
+	// Warning is a countdown running low and a skipped task.
+	Warning string
+	// Error is a countdown nearly out and a cancelled task.
+	Error     string
+	Completed string
+}
+
+// colorSchemes are the schemes built in for --color-scheme.
+var colorSchemes = map[string]ColorScheme{
+	"default":   {Running: "32", Paused: "36", Warning: "33", Error: "31", Completed: "32"},
+	"solarized": {Running: "38;5;64", Paused: "38;5;33", Warning: "38;5;136", Error: "38;5;160", Completed: "38;5;37"},
+	"dracula":   {Running: "38;5;84", Paused: "38;5;141", Warning: "38;5;215", Error: "38;5;203", Completed: "38;5;117"},
+	"nord":      {Running: "38;5;116", Paused: "38;5;110", Warning: "38;5;222", Error: "38;5;131", Completed: "38;5;144"},
+}
+
+// colors is the scheme in use, chosen with --color-scheme.
+var colors = colorSchemes["default"]
+
+func colorSchemesPath() string {
+	return filepath.Join(configDir(), "colors.yaml")
+}
+
+// lookupColorScheme returns the built-in scheme called name or, failing
+// that, the one defined in colorSchemesPath.
+func lookupColorScheme(name string) (ColorScheme, error) {
+	if scheme, ok := colorSchemes[name]; ok {
+		return scheme, nil
+	}
+
+	path := colorSchemesPath()
+	custom, err := loadColorSchemes(path)
+	if err != nil {
+		return ColorScheme{}, err
+	}
+	if scheme, ok := custom[name]; ok {
+		return scheme, nil
+	}
+
+	names := make([]string, 0, len(colorSchemes)+len(custom))
+	for n := range colorSchemes {
+		names = append(names, n)
+	}
+	for n := range custom {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return ColorScheme{}, fmt.Errorf("unknown color scheme %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// loadColorSchemes reads custom schemes from a YAML file of named
+// sections of roles. Roles left out keep the default scheme's colour:
+//
+//	mine:
+//	  running: 38;5;46
+//	  paused: 35
+//	  warning: 38;5;208
+//	  error: 1;31
+//	  completed: 38;5;46
+//
+// A missing file defines no schemes.
+func loadColorSchemes(path string) (map[string]ColorScheme, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	schemes := make(map[string]ColorScheme)
+	var name string
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"key: value\"", path, lineNo)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		// An unindented key starts a new scheme.
+		if raw[0] != ' ' && raw[0] != '\t' {
+			if value != "" {
+				return nil, fmt.Errorf("%s:%d: expected a scheme name followed by its roles", path, lineNo)
+			}
+			name = key
+			schemes[name] = colorSchemes["default"]
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%s:%d: role outside a scheme", path, lineNo)
+		}
+		if value == "" || strings.Trim(value, "0123456789;") != "" {
+			return nil, fmt.Errorf("%s:%d: %q is not an SGR code such as 32 or 38;5;208", path, lineNo, value)
+		}
+
+		scheme := schemes[name]
+		switch key {
+		case "running":
+			scheme.Running = value
+		case "paused":
+			scheme.Paused = value
+		case "warning":
+			scheme.Warning = value
+		case "error":
+			scheme.Error = value
+		case "completed":
+			scheme.Completed = value
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown role %q (want running, paused, warning, error or completed)", path, lineNo, key)
+		}
+		schemes[name] = scheme
+	}
+	return schemes, scanner.Err()
+}
+
+// colorize wraps s in the ANSI escape codes for color unless colours are
+// disabled.
+func colorize(color, s string) string {
+	if !colorEnabled {
+		return s
+	}
+	return "\033[" + color + "m" + s + "\033[0m"
+}
+
+// remainingColor picks the colour for a countdown from the share of
+// duration still remaining: running above half, warning down to a fifth
+// and error below that.
+func remainingColor(remaining, duration time.Duration) string {
+	switch {
+	case duration <= 0 || remaining*5 < duration:
+		return colors.Error
+	case remaining*2 > duration:
+		return colors.Running
+	}
+	return colors.Warning
+}
@@ -0,0 +1,766 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+// dryRun is set by --dry-run: commands are validated and queued but no
+// timer is started and nothing is written to disk.
+var dryRun bool
+
+// maxUndo is how many add and remove commands undo can reverse.
+const maxUndo = 10
+
+// UndoEntry records an add or remove command so undo can reverse it.
+// Index is where a removed task sat in the queue.
+type UndoEntry struct {
+	Command string
+	Task    Task
+	Index   int
+}
+
+// undoStack holds the most recent UndoEntry last.
+var undoStack []UndoEntry
+
+func pushUndo(entry UndoEntry) {
+	undoStack = append(undoStack, entry)
+	if len(undoStack) > maxUndo {
+		undoStack = undoStack[len(undoStack)-maxUndo:]
+	}
+}
+
+// confirmation, when set, receives the answer to a yes/no question asked
+// by an earlier command. Lines that are not an answer, such as commands
+// queued from a watched file, run as usual and leave the question open.
+var confirmation func(yes bool)
+
+// processCommand runs one line of user input and reports whether the
+// session should keep going.
+func processCommand(t *Timer, cmd string) bool {
+	if confirm := confirmation; confirm != nil {
+		switch strings.ToLower(strings.TrimSpace(cmd)) {
+		case "y", "yes":
+			confirmation = nil
+			confirm(true)
+			return true
+		case "", "n", "no":
+			confirmation = nil
+			confirm(false)
+			return true
+		}
+	}
+	fields, err := splitCommand(cmd)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+	if len(fields) == 0 {
+		return true
+	}
+	if fields, err = expandAlias(fields); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return true
+	}
+
+	args := fields[1:]
+	name := strings.ToLower(fields[0])
+	if dryRun && simulateControl(name, args) {
+		return true
+	}
+	c := lookupCommand(name)
+	if c == nil {
+		fmt.Printf("Unknown command %q. Type 'help' for the list of commands.\n", fields[0])
+		return true
+	}
+	return runCommand(t, c, args)
+}
+
+// errAddUsage is returned by parseTask for arguments with no task name
+// or no duration.
+var errAddUsage = errors.New("Invalid command format. Use: add <task name> [at HH:MM] <flags|duration> [--priority high|normal|low] [--repeat <n>|--repeat-forever] [--tag <label>]... [--project <name>]")
+
+func addTask(t *Timer, args []string) error {
+	task, err := parseTask(args, !t.Config.CountUp)
+	if err == nil {
+		err = t.CheckTask(task)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.Add(task)
+	pushUndo(UndoEntry{Command: "add", Task: task})
+	verb := "Added"
+	if dryRun {
+		verb = "Would add"
+	}
+	length := task.Duration.Round(time.Second).String()
+	if task.Duration == 0 {
+		length = "open-ended"
+	}
+	fmt.Printf("%s task: %s (%s%s)\n", verb, task.Name, length, startString(task.StartAt))
+	return nil
+}
+
+// splitCommand splits a line of input into words at spaces, as a shell
+// does: text in single quotes is taken as it is, text in double quotes
+// may escape a quote or backslash with a backslash, and outside quotes a
+// backslash escapes the next character. So `add 'Read chapter 3' 45m`
+// names a task "Read chapter 3".
+func splitCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	switch {
+	case quote != 0:
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	case escaped:
+		return nil, errors.New("backslash at end of line")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// parseTask builds a task from the arguments of an add command. The
+// duration may be left out unless needDuration is set.
+func parseTask(args []string, needDuration bool) (Task, error) {
+	priorityName, args, _, err := takeOption(args, "priority")
+	if err != nil {
+		return Task{}, err
+	}
+	priority, err := parsePriority(priorityName)
+	if err != nil {
+		return Task{}, err
+	}
+
+	repeatCount := 0
+	repeatValue, args, hasRepeat, err := takeOption(args, "repeat")
+	if err == nil && hasRepeat {
+		repeatCount, err = strconv.Atoi(repeatValue)
+		if err != nil || repeatCount < 1 {
+			err = fmt.Errorf("--repeat needs a positive count, got %q", repeatValue)
+		}
+	}
+	if err != nil {
+		return Task{}, err
+	}
+	repeatForever, args := takeBoolOption(args, "repeat-forever")
+
+	tags, args, err := takeRepeatedOption(args, "tag")
+	if err == nil {
+		err = checkTags(tags)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+
+	project, args, _, err := takeOption(args, "project")
+	if err == nil {
+		err = checkProject(project)
+	}
+	if err != nil {
+		return Task{}, err
+	}
+
+	startAt, args, err := takeStartTime(args, time.Now())
+	if err != nil {
+		return Task{}, err
+	}
+
+	flagsIndex := len(args)
+	for i, arg := range args {
+		if isDurationToken(arg) {
+			flagsIndex = i
+			break
+		}
+	}
+	if flagsIndex == 0 || (flagsIndex == len(args) && needDuration) {
+		return Task{}, errAddUsage
+	}
+
+	var duration time.Duration
+	if flagsIndex < len(args) {
+		duration, err = parseDuration(strings.Join(args[flagsIndex:], " "))
+		if err != nil {
+			return Task{}, err
+		}
+		if duration <= 0 {
+			return Task{}, errors.New("duration must be positive")
+		}
+	}
+
+	name := strings.Join(args[:flagsIndex], " ")
+	if err := checkName(name); err != nil {
+		return Task{}, err
+	}
+	return Task{
+		Name:          name,
+		Duration:      duration,
+		Priority:      priority,
+		RepeatCount:   repeatCount,
+		RepeatForever: repeatForever,
+		StartAt:       startAt,
+		Tags:          tags,
+		Project:       project,
+	}, nil
+}
+
+func extendTimer(t *Timer, args []string) error {
+	duration, err := parseDuration(strings.Join(args, " "))
+	if err == nil && duration <= 0 {
+		err = fmt.Errorf("duration must be positive")
+	}
+	if err == nil {
+		err = t.Extend(duration)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot extend: %w", err)
+	}
+	fmt.Printf("Extended by %s\n", duration.Round(time.Second))
+	return nil
+}
+
+func shortenTimer(t *Timer, args []string) error {
+	duration, err := parseDuration(strings.Join(args, " "))
+	if err == nil && duration <= 0 {
+		err = fmt.Errorf("duration must be positive")
+	}
+	if err == nil {
+		err = t.Shorten(duration)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot shorten: %w", err)
+	}
+	fmt.Printf("Shortened by %s\n", duration.Round(time.Second))
+	return nil
+}
+
+func removeTask(t *Timer, args []string) error {
+	i, err := taskNumber(args[0])
+	var task Task
+	if err == nil {
+		task, err = t.Remove(i)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot remove task: %w", err)
+	}
+	pushUndo(UndoEntry{Command: "remove", Task: task, Index: i})
+	fmt.Printf("Removed task: %s\n", task.Name)
+	return nil
+}
+
+// undo reverses the most recent add or remove.
+func undo(t *Timer, args []string) error {
+	if len(undoStack) == 0 {
+		fmt.Println("Nothing to undo.")
+		return nil
+	}
+	entry := undoStack[len(undoStack)-1]
+	undoStack = undoStack[:len(undoStack)-1]
+
+	switch entry.Command {
+	case "add":
+		if err := t.Withdraw(entry.Task); err != nil {
+			return fmt.Errorf("cannot undo add: %w", err)
+		}
+		fmt.Printf("Undid add: removed task %s\n", entry.Task.Name)
+	case "remove":
+		i := t.Insert(entry.Index, entry.Task)
+		fmt.Printf("Undid remove: restored task %s at position %d\n", entry.Task.Name, i+1)
+	}
+	return nil
+}
+
+func renameTask(t *Timer, args []string) error {
+	name := strings.Join(args[1:], " ")
+	err := checkName(name)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(args[0], "current") {
+		err = t.RenameCurrent(name)
+	} else {
+		var i int
+		i, err = taskNumber(args[0])
+		if err == nil {
+			err = t.Rename(i, name)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot rename task: %w", err)
+	}
+	fmt.Printf("Renamed task %s to: %s\n", args[0], name)
+	return nil
+}
+
+func swapTasks(t *Timer, args []string) error {
+	i, err := taskNumber(args[0])
+	var j int
+	if err == nil {
+		j, err = taskNumber(args[1])
+	}
+	if err == nil {
+		err = t.Swap(i, j)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot swap tasks: %w", err)
+	}
+	fmt.Printf("Swapped tasks %d and %d\n", i+1, j+1)
+	listTasks(t)
+	return nil
+}
+
+func resetHistory(t *Timer, args []string) error {
+	yes, args := takeBoolOption(args, "yes")
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	archive := func(yes bool) {
+		if !yes {
+			fmt.Println("History left unchanged")
+			return
+		}
+		if dryRun {
+			fmt.Println("Would archive the history file and start a new one")
+			return
+		}
+		path, err := t.ArchiveHistory()
+		if err != nil {
+			fmt.Printf("Error archiving history: %v\n", err)
+			return
+		}
+		fmt.Printf("History archived to %s\n", path)
+	}
+	if yes {
+		archive(true)
+		return nil
+	}
+	// Piped input has nobody to answer, and the next line would be taken
+	// for the answer.
+	if !isTerminal(os.Stdin) {
+		return errors.New("reset-history needs --yes when input is not a terminal")
+	}
+	fmt.Printf("Archive %s and start a new history? [y/N] ", t.Config.HistoryFile)
+	confirmation = archive
+	return nil
+}
+
+// backupHistory handles backup-history --s3-bucket <bucket> [--s3-key
+// <key>] [--compress]. The key defaults to the history file's name, with
+// .gz added when compressing.
+func backupHistory(t *Timer, args []string) error {
+	compress, args := takeBoolOption(args, "compress")
+	bucket, args, _, err := takeOption(args, "s3-bucket")
+	var key string
+	if err == nil {
+		key, args, _, err = takeOption(args, "s3-key")
+	}
+	if err != nil || bucket == "" || len(args) != 0 {
+		return errUsage
+	}
+	if key == "" {
+		key = filepath.Base(t.Config.HistoryFile)
+		if compress {
+			key += ".gz"
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Would upload %s to s3://%s/%s\n", t.Config.HistoryFile, bucket, key)
+		return nil
+	}
+	size, etag, err := t.BackupHistory(bucket, key, compress)
+	if err != nil {
+		return fmt.Errorf("cannot back up history: %w", err)
+	}
+	fmt.Printf("Uploaded %d bytes to s3://%s/%s (ETag %s)\n", size, bucket, key, etag)
+	return nil
+}
+
+// searchTasks handles search <term> [--regex], showing the history entries
+// with matching names.
+func searchTasks(t *Timer, args []string) error {
+	useRegex, args := takeBoolOption(args, "regex")
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	term := strings.Join(args, " ")
+	var re *regexp.Regexp
+	if useRegex {
+		var err error
+		if re, err = regexp.Compile(term); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	}
+	entries, err := t.History()
+	if err != nil {
+		return fmt.Errorf("cannot read history: %w", err)
+	}
+	found := searchHistory(entries, term, re)
+	if len(found) == 0 {
+		fmt.Printf("No tasks matching '%s'\n", term)
+		return nil
+	}
+	writeHistoryText(found)
+	return nil
+}
+
+func moveTask(t *Timer, args []string) error {
+	i, err := taskNumber(args[0])
+	var j int
+	if err == nil {
+		j, err = taskNumber(args[1])
+	}
+	if err == nil {
+		err = t.Move(i, j)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot move task: %w", err)
+	}
+	fmt.Printf("Moved task %d to position %d\n", i+1, j+1)
+	listTasks(t)
+	return nil
+}
+
+func duplicateTask(t *Timer, args []string) error {
+	var n int
+	var err error
+	if strings.EqualFold(args[0], "current") || args[0] == "0" {
+		n, err = t.DuplicateCurrent()
+	} else {
+		var i int
+		i, err = taskNumber(args[0])
+		if err == nil {
+			n, err = t.Duplicate(i)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("cannot duplicate task: %w", err)
+	}
+	fmt.Printf("Duplicated task %s; queue now has %d task(s)\n", args[0], n)
+	return nil
+}
+
+// listTasks prints the running and pending tasks. Given tags, it shows
+// only the tasks carrying any of them, keeping their queue numbers.
+func listTasks(t *Timer, tags ...string) {
+	running := t.Running()
+	queue := t.Queue()
+
+	if len(running) == 0 && len(queue) == 0 {
+		fmt.Println("Queue is empty")
+		return
+	}
+
+	var matched int
+	for _, task := range append(running, queue...) {
+		if hasAnyTag(task.Tags, tags) {
+			matched++
+		}
+	}
+	if matched == 0 {
+		fmt.Printf("No tasks matching tag %s\n", tagList(tags))
+		return
+	}
+
+	// total counts what is left of the running tasks and all of the
+	// pending ones.
+	var total time.Duration
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTask\tPriority\tRepeat\tDuration\tTags")
+	for _, task := range running {
+		if !hasAnyTag(task.Tags, tags) {
+			continue
+		}
+		total += task.Remaining
+		fmt.Fprintf(w, "[running]\t%s\t%s\t%s\t%s (%s remaining)\t%s\n",
+			task.Name, priorityString(task.Priority), repeatString(task),
+			task.Duration.Round(time.Second), task.Remaining.Round(time.Second), strings.Join(task.Tags, ", "))
+	}
+	for i, task := range queue {
+		if !hasAnyTag(task.Tags, tags) {
+			continue
+		}
+		total += task.Duration
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s%s\t%s\n",
+			i+1, task.Name, priorityString(task.Priority), repeatString(task),
+			task.Duration.Round(time.Second), startString(task.StartAt), strings.Join(task.Tags, ", "))
+	}
+	w.Flush()
+
+	if len(queue) == 0 {
+		fmt.Println("No pending tasks")
+	}
+	fmt.Printf("Queue: %d task(s), total %s\n", matched, shortDuration(total.Round(time.Second)))
+}
+
+// simulateControl stands in for the commands that act on the running
+// timer, which never starts in a dry run. It reports whether cmd was one
+// of them.
+func simulateControl(cmd string, args []string) bool {
+	switch cmd {
+	case "pause", "resume", "cancel", "skip", "done":
+		fmt.Printf("Would %s the running timer\n", cmd)
+	case "note":
+		fmt.Println("Would add the note to the running task")
+	case "extend", "shorten":
+		duration, err := parseDuration(strings.Join(args, " "))
+		if err == nil && duration <= 0 {
+			err = fmt.Errorf("duration must be positive")
+		}
+		if err != nil {
+			printDurationError("Cannot "+cmd, err)
+			return true
+		}
+		fmt.Printf("Would %s the running timer by %s\n", cmd, duration.Round(time.Second))
+	default:
+		return false
+	}
+	return true
+}
+
+// printDryRunSummary reports what a dry run left in the queue.
+func printDryRunSummary(t *Timer) {
+	queue := t.Queue()
+	var total time.Duration
+	for _, task := range queue {
+		total += task.Duration
+	}
+	fmt.Printf("Dry run: %d task(s) would run for %s in total; no timers were started\n",
+		len(queue), total.Round(time.Second))
+}
+
+// printDurationError reports err after prefix, adding the accepted
+// duration syntaxes when the duration itself could not be parsed.
+func printDurationError(prefix string, err error) {
+	fmt.Printf("%s: %v\n", prefix, err)
+	var parseErr *DurationParseError
+	if errors.As(err, &parseErr) {
+		fmt.Println("Durations look like 25m, 1h30m, PT1H30M or -h 1 -m 30 -s 0")
+	}
+}
+
+// taskNumber converts a 1-based position typed by the user into a queue
+// index. Range checks are left to the Timer.
+func taskNumber(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid task number %q", arg)
+	}
+	return n - 1, nil
+}
+
+// takeOption removes "--name value", "-name value" or "--name=value" from
+// args, returning the value and the remaining arguments.
+func takeOption(args []string, name string) (string, []string, bool, error) {
+	for i, arg := range args {
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || flagName != name {
+			continue
+		}
+
+		rest := append([]string(nil), args[:i]...)
+		if hasValue {
+			return value, append(rest, args[i+1:]...), true, nil
+		}
+		if i+1 >= len(args) {
+			return "", args, false, fmt.Errorf("--%s requires a value", name)
+		}
+		return args[i+1], append(rest, args[i+2:]...), true, nil
+	}
+	return "", args, false, nil
+}
+
+// takeStartTime removes "at HH:MM" from args and returns the next time
+// the clock reads HH:MM after now: today, or tomorrow if already past.
+func takeStartTime(args []string, now time.Time) (time.Time, []string, error) {
+	for i := 0; i+1 < len(args); i++ {
+		if !strings.EqualFold(args[i], "at") || !strings.Contains(args[i+1], ":") {
+			continue
+		}
+
+		clock, err := time.Parse("15:04", args[i+1])
+		if err != nil {
+			return time.Time{}, args, fmt.Errorf("invalid start time %q, expected HH:MM", args[i+1])
+		}
+		startAt := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !startAt.After(now) {
+			startAt = startAt.AddDate(0, 0, 1)
+		}
+		return startAt, append(append([]string(nil), args[:i]...), args[i+2:]...), nil
+	}
+	return time.Time{}, args, nil
+}
+
+// takeRepeatedOption removes every occurrence of option name from args,
+// returning the values in order.
+func takeRepeatedOption(args []string, name string) ([]string, []string, error) {
+	var values []string
+	for {
+		value, rest, ok, err := takeOption(args, name)
+		if err != nil {
+			return nil, args, err
+		}
+		if !ok {
+			return values, args, nil
+		}
+		values = append(values, value)
+		args = rest
+	}
+}
+
+// takeBoolOption removes "--name" or "-name" from args and reports whether
+// it was present.
+func takeBoolOption(args []string, name string) (bool, []string) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") && strings.TrimLeft(arg, "-") == name {
+			return true, append(append([]string(nil), args[:i]...), args[i+1:]...)
+		}
+	}
+	return false, args
+}
+
+// checkName rejects task names that would break the history line, where
+// fields are separated by '|' and entries by newlines.
+func checkName(name string) error {
+	if strings.ContainsAny(name, "|\r\n") {
+		return fmt.Errorf("invalid task name %q: names must not contain '|' or a line break", name)
+	}
+	return nil
+}
+
+// checkTags rejects tags that cannot be stored in the history file, where
+// they are separated by commas.
+func checkTags(tags []string) error {
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" || strings.ContainsAny(tag, ",|\n") {
+			return fmt.Errorf("invalid tag %q: tags must not be blank or contain ',' or '|'", tag)
+		}
+	}
+	return nil
+}
+
+// hasAnyTag reports whether tags includes any of want, ignoring case. An
+// empty want matches everything.
+func hasAnyTag(tags, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, w := range want {
+			if strings.EqualFold(tag, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkProject rejects project names that would break the history line,
+// where fields are separated by '|' and entries by newlines.
+func checkProject(project string) error {
+	if strings.ContainsAny(project, "|\n") {
+		return fmt.Errorf("invalid project %q: projects must not contain '|' or a newline", project)
+	}
+	return nil
+}
+
+// tagList quotes tags for messages: 'a', or 'a' or 'b'.
+func tagList(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		quoted[i] = "'" + tag + "'"
+	}
+	return strings.Join(quoted, " or ")
+}
+
+// startString describes when a scheduled task starts, or is empty for
+// tasks that start as soon as they reach the front of the queue.
+func startString(startAt time.Time) string {
+	if startAt.IsZero() {
+		return ""
+	}
+	return " at " + startAt.Format("15:04")
+}
+
+func parsePriority(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "high":
+		return PriorityHigh, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	default:
+		return 0, fmt.Errorf("unknown priority %q (want high, normal or low)", s)
+	}
+}
+
+func priorityString(priority int) string {
+	switch {
+	case priority > PriorityNormal:
+		return "high"
+	case priority < PriorityNormal:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// repeatString describes the runs a task has left, counting the current one.
+func repeatString(task Task) string {
+	switch {
+	case task.RepeatForever:
+		return "forever"
+	case task.RepeatCount > 1:
+		return fmt.Sprintf("%dx", task.RepeatCount)
+	default:
+		return ""
+	}
+}
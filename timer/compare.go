@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// period is a range of days, both included.
+type period struct {
+	from, to time.Time
+}
+
+func (p period) String() string {
+	return p.from.Format(time.DateOnly) + " to " + p.to.Format(time.DateOnly)
+}
+
+// periodStats sums up the tasks completed in a period.
+type periodStats struct {
+	tasks int
+	total time.Duration
+	types int
+}
+
+func (s periodStats) average() time.Duration {
+	if s.tasks == 0 {
+		return 0
+	}
+	return (s.total / time.Duration(s.tasks)).Round(time.Second)
+}
+
+// compareCommand handles "timer compare --from <date> --to <date> vs
+// --from <date> --to <date>", or the same with --period1 <from>..<to>
+// --period2 <from>..<to>, setting the two periods' history side by side.
+func compareCommand(t *Timer, args []string) error {
+	const usage = "usage: timer compare --from <date> --to <date> vs --from <date> --to <date>, or --period1 <date>..<date> --period2 <date>..<date>"
+	var periods []period
+	if i := slices.Index(args, "vs"); i >= 0 {
+		for _, side := range [][]string{args[:i], args[i+1:]} {
+			from, side, _, err := takeOption(side, "from")
+			var to string
+			if err == nil {
+				to, side, _, err = takeOption(side, "to")
+			}
+			if err != nil {
+				return err
+			}
+			if from == "" || to == "" || len(side) != 0 {
+				return errors.New(usage)
+			}
+			p, err := parsePeriod(from, to)
+			if err != nil {
+				return err
+			}
+			periods = append(periods, p)
+		}
+	} else {
+		for _, name := range []string{"period1", "period2"} {
+			var value string
+			var err error
+			value, args, _, err = takeOption(args, name)
+			if err != nil {
+				return err
+			}
+			from, to, ok := strings.Cut(value, "..")
+			if !ok {
+				return errors.New(usage)
+			}
+			p, err := parsePeriod(from, to)
+			if err != nil {
+				return fmt.Errorf("--%s: %w", name, err)
+			}
+			periods = append(periods, p)
+		}
+		if len(args) != 0 {
+			return errors.New(usage)
+		}
+	}
+
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	return showComparison(entries, periods[0], periods[1])
+}
+
+func parsePeriod(from, to string) (period, error) {
+	start, err := parseDate(from)
+	if err != nil {
+		return period{}, err
+	}
+	end, err := parseDate(to)
+	if err != nil {
+		return period{}, err
+	}
+	if end.Before(start) {
+		return period{}, fmt.Errorf("period ends on %s, before it starts on %s", to, from)
+	}
+	return period{start, end}, nil
+}
+
+// statsFor sums up the completed entries in p.
+func statsFor(entries []HistoryEntry, p period) periodStats {
+	var stats periodStats
+	names := make(map[string]bool)
+	for _, e := range filterHistory(entries, p.from, p.to, nil) {
+		if e.Status != StatusCompleted {
+			continue
+		}
+		stats.tasks++
+		stats.total += e.Duration
+		names[strings.ToLower(e.Name)] = true
+	}
+	stats.types = len(names)
+	return stats
+}
+
+func showComparison(entries []HistoryEntry, first, second period) error {
+	a, b := statsFor(entries, first), statsFor(entries, second)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\t%s\t%s\tChange\n", first, second)
+	fmt.Fprintf(w, "Tasks completed\t%d\t%d\t%s\n", a.tasks, b.tasks, percentChange(float64(a.tasks), float64(b.tasks)))
+	fmt.Fprintf(w, "Total time\t%s\t%s\t%s\n", a.total, b.total, percentChange(float64(a.total), float64(b.total)))
+	fmt.Fprintf(w, "Task types\t%d\t%d\t%s\n", a.types, b.types, percentChange(float64(a.types), float64(b.types)))
+	fmt.Fprintf(w, "Average length\t%s\t%s\t%s\n", a.average(), b.average(), percentChange(float64(a.average()), float64(b.average())))
+	return w.Flush()
+}
+
+// percentChange describes the change from a to b, as in "↑ 25%".
+func percentChange(a, b float64) string {
+	switch {
+	case a == b:
+		return "0%"
+	case a == 0:
+		return "↑ new"
+	}
+	change := (b - a) / a * 100
+	arrow := "↑"
+	if change < 0 {
+		arrow = "↓"
+	}
+	return fmt.Sprintf("%s %.0f%%", arrow, math.Abs(change))
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// completeTagsFlag names the hidden flag the completion scripts run to
+// list the tags in the history.
+const completeTagsFlag = "complete-tags"
+
+// completionValues are the values offered after flags that take one of a
+// fixed set.
+var completionValues = map[string][]string{
+	"format":          {"text", "json", "csv", "markdown"},
+	"sort":            {"name", "date", "duration"},
+	"log-format":      {LogFormatPipe, LogFormatJSONL},
+	"log-level":       {"debug", "info", "warn", "error"},
+	"log-encoding":    {LogEncodingText, LogEncodingJSON},
+	"countdown-style": {CountdownText, CountdownBar, CountdownSpinner},
+	"completion":      {"bash", "zsh", "fish"},
+}
+
+// completionFiles are the flags that take a file name.
+var completionFiles = map[string]bool{
+	"history-file": true, "sound-file": true, "heatmap-out": true, "output": true,
+	"task-file": true, "watch-file": true, "profile": true, "memprofile": true,
+	"config": true,
+}
+
+type completionFlag struct {
+	Name, Usage string
+	Values      []string
+	File, Tags  bool
+	TakesValue  bool
+}
+
+type completionData struct {
+	Commands []string
+	Flags    []completionFlag
+}
+
+var completionTemplates = map[string]string{
+	"bash": `# bash completion for timer. Load it from ~/.bashrc with:
+#   eval "$(timer --completion bash)"
+_timer() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	case $prev in
+{{- range .Flags}}{{if .Tags}}
+	--{{.Name}}|-{{.Name}})
+		COMPREPLY=($(compgen -W "$(timer --` + completeTagsFlag + ` 2>/dev/null)" -- "$cur"))
+		return ;;
+{{- else if .File}}
+	--{{.Name}}|-{{.Name}})
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return ;;
+{{- else if .Values}}
+	--{{.Name}}|-{{.Name}})
+		COMPREPLY=($(compgen -W "{{join .Values}}" -- "$cur"))
+		return ;;
+{{- else if .TakesValue}}
+	--{{.Name}}|-{{.Name}})
+		return ;;
+{{- end}}{{end}}
+	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "{{range .Flags}} --{{.Name}}{{end}}" -- "$cur"))
+	else
+		COMPREPLY=($(compgen -W "{{join .Commands}}" -- "$cur"))
+	fi
+}
+complete -o default -F _timer timer
+`,
+
+	"zsh": `#compdef timer
+# zsh completion for timer. Load it from ~/.zshrc, after compinit, with:
+#   eval "$(timer --completion zsh)"
+_timer() {
+	case ${words[CURRENT-1]} in
+{{- range .Flags}}{{if .Tags}}
+	--{{.Name}}|-{{.Name}})
+		compadd -- ${(f)"$(timer --` + completeTagsFlag + ` 2>/dev/null)"}
+		return ;;
+{{- else if .File}}
+	--{{.Name}}|-{{.Name}})
+		_files
+		return ;;
+{{- else if .Values}}
+	--{{.Name}}|-{{.Name}})
+		compadd -- {{join .Values}}
+		return ;;
+{{- else if .TakesValue}}
+	--{{.Name}}|-{{.Name}})
+		return ;;
+{{- end}}{{end}}
+	esac
+	if [[ $PREFIX == -* ]]; then
+		compadd -- {{range .Flags}} --{{.Name}}{{end}}
+	else
+		compadd -- {{join .Commands}}
+		_files
+	fi
+}
+compdef _timer timer
+`,
+
+	"fish": `# fish completion for timer. Load it from ~/.config/fish/config.fish with:
+#   timer --completion fish | source
+complete -c timer -f
+complete -c timer -n __fish_use_subcommand -a '{{join .Commands}}'
+{{- range .Flags}}
+complete -c timer -l {{.Name}}
+{{- if .Tags}} -x -a '(timer --` + completeTagsFlag + ` 2>/dev/null)'
+{{- else if .File}} -r -F
+{{- else if .Values}} -x -a '{{join .Values}}'
+{{- else if .TakesValue}} -x
+{{- end}} -d {{fishQuote .Usage}}
+{{- end}}
+`,
+}
+
+// printCompletion writes the completion script for shell, built from the
+// flags defined on the command line and the interactive commands.
+func printCompletion(w io.Writer, shell string) error {
+	text, ok := completionTemplates[shell]
+	if !ok {
+		return fmt.Errorf("unknown shell %q (want bash, zsh or fish)", shell)
+	}
+	tmpl := template.Must(template.New(shell).Funcs(template.FuncMap{
+		"join": func(words []string) string { return strings.Join(words, " ") },
+		"fishQuote": func(s string) string {
+			return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+		},
+	}).Parse(text))
+
+	data := completionData{Commands: []string{"calendar", "compare", "export", "goal", "import", "longest", "project", "rate", "shortest", "validate"}}
+	for _, c := range commands {
+		data.Commands = append(data.Commands, c.Name())
+	}
+	slices.Sort(data.Commands)
+	var schemes []string
+	for name := range colorSchemes {
+		schemes = append(schemes, name)
+	}
+	slices.Sort(schemes)
+
+	flag.VisitAll(func(f *flag.Flag) {
+		// Shorthands are left to the long flags they stand for.
+		if strings.HasPrefix(f.Usage, "Internal:") || shorthands[f.Name] != "" {
+			return
+		}
+		values := completionValues[f.Name]
+		if f.Name == "color-scheme" {
+			values = schemes
+		}
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		data.Flags = append(data.Flags, completionFlag{
+			Name:       f.Name,
+			Usage:      f.Usage,
+			Values:     values,
+			File:       completionFiles[f.Name],
+			Tags:       f.Name == "tag",
+			TakesValue: !ok || !boolFlag.IsBoolFlag(),
+		})
+	})
+	return tmpl.Execute(w, data)
+}
+
+// printHistoryTags writes the tags used in t's history, one per line.
+func printHistoryTags(w io.Writer, t *Timer) error {
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	var tags []string
+	for _, entry := range entries {
+		for _, tag := range entry.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	for _, tag := range tags {
+		fmt.Fprintln(w, tag)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configDir is where the timer keeps its per-user files.
+func configDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, ".config", "timer")
+}
+
+func defaultConfigPath() string {
+	return filepath.Join(configDir(), "config.yaml")
+}
+
+// loadConfig reads a flat YAML file of "key: value" pairs. Keys are the
+// long flag names, with underscores accepted in place of dashes:
+//
+//	work: 50m
+//	short_break: 10m
+//	history-file: /home/me/.local/share/timer/history.log
+//	sound: true
+//
+// A missing file yields no settings.
+func loadConfig(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"key: value\"", path, lineNo)
+		}
+		key = strings.ReplaceAll(strings.TrimSpace(key), "_", "-")
+		value = strings.TrimSpace(value)
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	return values, scanner.Err()
+}
+
+// applyConfig copies config values onto the matching flags, skipping any
+// flag given explicitly on the command line.
+func applyConfig(path string, values map[string]string) error {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+		// A shorthand counts as its long flag.
+		if long, ok := shorthands[f.Name]; ok {
+			explicit[long] = true
+		}
+	})
+
+	for key, value := range values {
+		if key == "config" || explicit[key] {
+			continue
+		}
+		if flag.Lookup(key) == nil {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := flag.Set(key, value); err != nil {
+			return fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Countdown styles accepted by --countdown-style.
+const (
+	CountdownText    = "text"
+	CountdownBar     = "bar"
+	CountdownSpinner = "spinner"
+)
+
+const defaultBarWidth = 40
+
+// minTickInterval keeps --tick-interval from flooding the terminal.
+const minTickInterval = 100 * time.Millisecond
+
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+func validateCountdownStyle(style string) error {
+	switch style {
+	case "", CountdownText, CountdownBar, CountdownSpinner:
+		return nil
+	}
+	return fmt.Errorf("unknown countdown style %q, expected text, bar or spinner", style)
+}
+
+// countdownLine renders one tick of task's countdown in the configured
+// style. frame counts the ticks so far and drives the spinner.
+func (t *Timer) countdownLine(task *Task, remaining time.Duration, frame int) string {
+	t.mu.Lock()
+	name, duration := displayName(task), task.Duration
+	t.mu.Unlock()
+
+	left := colorize(remainingColor(remaining, duration), fmt.Sprintf("%-10s", remaining))
+
+	switch t.Config.CountdownStyle {
+	case CountdownBar:
+		width := t.Config.BarWidth
+		if width < 1 {
+			width = defaultBarWidth
+		}
+		done := 1.0
+		if duration > 0 {
+			done = min(max(1-float64(remaining)/float64(duration), 0), 1)
+		}
+		filled := int(done * float64(width))
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+		return fmt.Sprintf("\r%s: [%s] %3d%% %s remaining", name, bar, int(done*100), left)
+	case CountdownSpinner:
+		return fmt.Sprintf("\r%s: %c %s remaining", name, spinnerFrames[frame%len(spinnerFrames)], left)
+	}
+	return fmt.Sprintf("\r%s: %s remaining", name, left)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// cronSchedules are the schedules started by --cron, for list --schedules.
+var cronSchedules []*cronSchedule
+
+// cronSchedule adds a copy of task to the queue at every time matching a
+// cron spec.
+type cronSchedule struct {
+	spec string
+	task Task
+
+	// The times matched, as sets of allowed values.
+	second, minute, hour, dom, month, dow []bool
+	// anyDOM and anyDOW record a "*" day field. When both day fields are
+	// restricted, a day matching either is enough, as in cron.
+	anyDOM, anyDOW bool
+}
+
+// cronDescriptors are the shorthands accepted in place of a spec.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// parseCron reads the value of a --cron flag: a spec optionally followed
+// by the task to add, which otherwise comes from defaultTask. The spec
+// has six fields, second minute hour day-of-month month day-of-week, or
+// the usual five without the seconds, or is a descriptor like @hourly.
+func parseCron(value string, defaultTask []string) (*cronSchedule, error) {
+	fields, err := splitCommand(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty cron spec")
+	}
+
+	var spec, taskArgs []string
+	if expanded, ok := cronDescriptors[strings.ToLower(fields[0])]; ok {
+		spec, taskArgs = strings.Fields(expanded), fields[1:]
+		fields = fields[:1]
+	} else {
+		n := 5
+		if len(fields) >= 6 && isCronField(fields[5]) {
+			n = 6
+		}
+		if len(fields) < n {
+			return nil, fmt.Errorf("cron spec %q needs 5 or 6 fields", value)
+		}
+		spec, taskArgs = fields[:n], fields[n:]
+		fields = fields[:n]
+		if n == 5 {
+			spec = append([]string{"0"}, spec...)
+		}
+	}
+	if len(taskArgs) == 0 {
+		taskArgs = defaultTask
+	}
+	if len(taskArgs) == 0 {
+		return nil, fmt.Errorf("cron spec %q has no task; give one after the spec or after the flags", value)
+	}
+
+	task, err := parseTask(taskArgs, true)
+	if err != nil {
+		return nil, err
+	}
+	s := &cronSchedule{spec: strings.Join(fields, " "), task: task}
+
+	ranges := []struct {
+		field    *[]bool
+		min, max int
+		name     string
+	}{
+		{&s.second, 0, 59, "second"},
+		{&s.minute, 0, 59, "minute"},
+		{&s.hour, 0, 23, "hour"},
+		{&s.dom, 1, 31, "day of month"},
+		{&s.month, 1, 12, "month"},
+		{&s.dow, 0, 7, "day of week"},
+	}
+	for i, r := range ranges {
+		set, err := parseCronField(spec[i], r.min, r.max)
+		if err != nil {
+			return nil, fmt.Errorf("cron %s field %q: %w", r.name, spec[i], err)
+		}
+		*r.field = set
+	}
+	// Sunday is 0 or 7.
+	s.dow[0] = s.dow[0] || s.dow[7]
+	s.anyDOM = spec[3] == "*" || spec[3] == "?"
+	s.anyDOW = spec[5] == "*" || spec[5] == "?"
+	return s, nil
+}
+
+// isCronField reports whether s could be a field of a cron spec rather
+// than the start of a task name.
+func isCronField(s string) bool {
+	return strings.Trim(s, "0123456789*?/,-") == ""
+}
+
+// parseCronField returns the set of values from min to max matched by a
+// comma-separated list of "*", "n", "a-b", each optionally with a "/step".
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var errA, errB error
+			lo, errA = strconv.Atoi(a)
+			hi, errB = strconv.Atoi(b)
+			if errA != nil || errB != nil || lo > hi {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", rangePart)
+			}
+			lo = n
+			if !hasStep {
+				hi = n
+			}
+		}
+		if lo < min || hi > max {
+			return nil, fmt.Errorf("%s is outside %d-%d", rangePart, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matchesDay reports whether the day fields allow day.
+func (s *cronSchedule) matchesDay(day time.Time) bool {
+	dom, dow := s.dom[day.Day()], s.dow[day.Weekday()]
+	switch {
+	case s.anyDOM && s.anyDOW:
+		return true
+	case s.anyDOM:
+		return dow
+	case s.anyDOW:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first matching time after after, or the zero time if
+// none comes within five years.
+func (s *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Second).Add(time.Second)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !s.month[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minute[t.Minute()]:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case !s.second[t.Second()]:
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// run adds the task to t's queue at every matching time.
+func (s *cronSchedule) run(t *Timer) {
+	slog.Debug("cron goroutine started", "spec", s.spec)
+	for {
+		next := s.next(time.Now())
+		if next.IsZero() {
+			slog.Warn("cron spec never matches again", "spec", s.spec)
+			return
+		}
+		time.Sleep(time.Until(next))
+
+		task := s.task
+		task.Tags = slices.Clone(task.Tags)
+		if err := t.CheckTask(task); err != nil {
+			fmt.Printf("Skipped scheduled task %s: %v\n", task.Name, err)
+			continue
+		}
+		t.Add(task)
+		fmt.Printf("Added scheduled task: %s (%s)\n", task.Name, task.Duration.Round(time.Second))
+	}
+}
+
+// listSchedules prints the schedules started by --cron and when each
+// next fires.
+func listSchedules() {
+	if len(cronSchedules) == 0 {
+		fmt.Println("No schedules")
+		return
+	}
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Spec\tTask\tDuration\tNext")
+	for _, s := range cronSchedules {
+		next := "never"
+		if at := s.next(now); !at.IsZero() {
+			next = at.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.spec, s.task.Name, s.task.Duration.Round(time.Second), next)
+	}
+	w.Flush()
+}
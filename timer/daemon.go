@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// daemonChildFlag marks the re-executed background process.
+const daemonChildFlag = "daemon-child"
+
+func runDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return filepath.Join(home, ".local", "run")
+}
+
+func pidFilePath() string {
+	return filepath.Join(runDir(), "timer.pid")
+}
+
+func daemonLogPath() string {
+	return filepath.Join(runDir(), "timer.log")
+}
+
+// startDaemon re-executes the timer in the background with the same
+// flags, detached from the terminal, and records its PID.
+func startDaemon() error {
+	if pid, err := readPIDFile(); err == nil && processAlive(pid) {
+		return fmt.Errorf("timer daemon already running (PID %d)", pid)
+	}
+
+	if err := os.MkdirAll(runDir(), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(daemonLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(exe, daemonArgs(os.Args[1:])...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	if err := os.WriteFile(pidFilePath(), []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Timer daemon started (PID %d), logging to %s\n", pid, daemonLogPath())
+	return cmd.Process.Release()
+}
+
+// daemonArgs swaps --daemon, however it was written, for the internal
+// child flag.
+func daemonArgs(args []string) []string {
+	out := []string{"--" + daemonChildFlag}
+	args = expandShortFlags(flag.CommandLine, args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return append(out, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name == "daemon" || shorthands[name] == "daemon" {
+			continue
+		}
+		out = append(out, arg)
+		// Values are copied as they are, even one that looks like -d.
+		if !hasValue && !isBoolFlag(flag.CommandLine, name) && flag.Lookup(name) != nil && i+1 < len(args) {
+			i++
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+// stopDaemon sends SIGTERM to the process named in the PID file.
+func stopDaemon() error {
+	pid, err := readPIDFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no timer daemon is running")
+		}
+		return err
+	}
+
+	if !processAlive(pid) {
+		os.Remove(pidFilePath())
+		return fmt.Errorf("timer daemon (PID %d) is not running", pid)
+	}
+	if err := terminate(pid); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped timer daemon (PID %d)\n", pid)
+	return nil
+}
+
+func readPIDFile() (int, error) {
+	data, err := os.ReadFile(pidFilePath())
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// runDaemon keeps the background process alive until it is told to
+// terminate. With no stdin, commands arrive on the IPC socket instead.
+func runDaemon(t *Timer) {
+	defer os.Remove(pidFilePath())
+
+	listener, err := listenIPC(t)
+	if err != nil {
+		slog.Warn("cannot listen for commands", "socket", socketPath(), "err", err)
+	} else {
+		defer os.Remove(socketPath())
+		defer listener.Close()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	sig := <-signals
+
+	fmt.Printf("Received %s, saving running tasks and shutting down\n", sig)
+	t.Interrupt()
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	isoDurationRe     = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	compactDurationRe = regexp.MustCompile(`^(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?$`)
+)
+
+// DurationParseError reports a duration that parseDuration could not
+// understand and why.
+type DurationParseError struct {
+	Input  string
+	Reason string
+}
+
+func (e *DurationParseError) Error() string {
+	return "invalid duration: " + e.Reason
+}
+
+func durationError(input, format string, args ...any) error {
+	return &DurationParseError{Input: input, Reason: fmt.Sprintf(format, args...)}
+}
+
+// durationFlag is an -h, -m or -s value. It keeps the reason a value was
+// rejected because the flag package only reports that parsing failed.
+type durationFlag struct {
+	name   string
+	value  int
+	reason string
+}
+
+func (f *durationFlag) String() string {
+	return strconv.Itoa(f.value)
+}
+
+func (f *durationFlag) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	switch {
+	case err != nil:
+		f.reason = fmt.Sprintf("flag -%s requires an integer, got '%s'", f.name, s)
+	case n < 0:
+		f.reason = fmt.Sprintf("flag -%s must not be negative, got %d", f.name, n)
+	default:
+		f.value = n
+		return nil
+	}
+	return errors.New(f.reason)
+}
+
+// parseDuration understands three syntaxes: flags (-h 1 -m 30), ISO 8601
+// (PT1H30M) and compact strings (1h30m). Errors are *DurationParseError.
+func parseDuration(input string) (time.Duration, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, durationError(input, "empty duration")
+	}
+	if isISODuration(input) {
+		return parseISODuration(input)
+	}
+	if isCompactDuration(input) {
+		return parseCompactDuration(input)
+	}
+
+	h := &durationFlag{name: "h"}
+	m := &durationFlag{name: "m"}
+	s := &durationFlag{name: "s"}
+	fs := flag.NewFlagSet("durationFlags", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(h, "h", "Hours")
+	fs.Var(m, "m", "Minutes")
+	fs.Var(s, "s", "Seconds")
+
+	if err := fs.Parse(strings.Fields(input)); err != nil {
+		for _, f := range []*durationFlag{h, m, s} {
+			if f.reason != "" {
+				return 0, durationError(input, "%s", f.reason)
+			}
+		}
+		return 0, durationError(input, "%v", err)
+	}
+	if fs.NArg() > 0 && fs.NFlag() == 0 {
+		return 0, durationError(input, "'%s' is not a duration such as 1h30m, 25m or 90s", input)
+	}
+	if fs.NArg() > 0 {
+		return 0, durationError(input, "unexpected argument '%s'", fs.Arg(0))
+	}
+
+	return time.Duration(h.value)*time.Hour +
+		time.Duration(m.value)*time.Minute +
+		time.Duration(s.value)*time.Second, nil
+}
+
+func isISODuration(input string) bool {
+	return strings.HasPrefix(strings.ToUpper(input), "P")
+}
+
+// parseISODuration accepts the day and time parts of an ISO 8601
+// duration, e.g. PT1H30M45S or P1DT2H.
+func parseISODuration(input string) (time.Duration, error) {
+	upper := strings.ToUpper(input)
+	m := isoDurationRe.FindStringSubmatch(upper)
+	if m == nil || strings.HasSuffix(upper, "T") {
+		return 0, durationError(input, "'%s' is not an ISO 8601 duration such as PT1H30M", input)
+	}
+
+	units := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var total time.Duration
+	var found bool
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return 0, durationError(input, "'%s' is out of range in '%s'", m[i+1], input)
+		}
+		total += time.Duration(n) * unit
+		found = true
+	}
+
+	if !found {
+		return 0, durationError(input, "'%s' has no days, hours, minutes or seconds", input)
+	}
+	return total, nil
+}
+
+func isCompactDuration(input string) bool {
+	return input != "" && input[0] >= '0' && input[0] <= '9'
+}
+
+// parseCompactDuration handles strings like 90s, 25m or 2h15m30s. Anything
+// time.ParseDuration rejects, such as "1H 30M", goes through the regex.
+func parseCompactDuration(input string) (time.Duration, error) {
+	if d, err := time.ParseDuration(input); err == nil {
+		return d, nil
+	}
+
+	normalized := strings.ToLower(strings.Join(strings.Fields(input), ""))
+	m := compactDurationRe.FindStringSubmatch(normalized)
+	if m == nil || normalized == "" {
+		return 0, durationError(input, "'%s' is not a duration such as 1h30m, 25m or 90s", input)
+	}
+
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	var total time.Duration
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return 0, durationError(input, "'%s' is out of range in '%s'", m[i+1], input)
+		}
+		total += time.Duration(n) * unit
+	}
+	return total, nil
+}
+
+// shortDuration formats d like time.Duration.String without trailing zero
+// units, e.g. 8h rather than 8h0m0s.
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// isDurationToken reports whether arg begins the duration part of an add
+// command, which separates it from the task name. Both duration patterns
+// let every part be left out, so words such as "P" and "PT" are only
+// durations with a number in them.
+func isDurationToken(arg string) bool {
+	if strings.HasPrefix(arg, "-") {
+		return true
+	}
+	return strings.ContainsAny(arg, "0123456789") &&
+		(isoDurationRe.MatchString(strings.ToUpper(arg)) || compactDurationRe.MatchString(strings.ToLower(arg)))
+}
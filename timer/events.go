@@ -0,0 +1,62 @@
+package main
+
+import "time"
+
+// EventType says what happened in an Event.
+type EventType string
+
+// Event types sent to subscribers.
+const (
+	EventTaskStarted   EventType = "task-started"
+	EventTaskCompleted EventType = "task-completed"
+	EventTaskSkipped   EventType = "task-skipped"
+	EventTaskCancelled EventType = "task-cancelled"
+	EventQueueEmpty    EventType = "queue-empty"
+	EventTick          EventType = "tick"
+)
+
+// Event is something that happened to a task, with the time it had left
+// at that moment. Task is the zero value for EventQueueEmpty.
+type Event struct {
+	Type      EventType
+	Task      Task
+	Remaining time.Duration
+}
+
+// Subscribe registers ch to receive every event from now on. Events are
+// dropped rather than waited for when ch is full.
+func (t *Timer) Subscribe(ch chan<- Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.subscribers == nil {
+		t.subscribers = make(map[chan<- Event]struct{})
+	}
+	t.subscribers[ch] = struct{}{}
+}
+
+// Unsubscribe stops sending events to ch.
+func (t *Timer) Unsubscribe(ch chan<- Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.subscribers, ch)
+}
+
+// emit sends an event about task to every subscriber without blocking.
+// t.mu must not be held.
+func (t *Timer) emit(typ EventType, task *Task) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	event := Event{Type: typ}
+	if task != nil {
+		event.Task = *task
+		event.Remaining = task.Remaining
+	}
+	for ch := range t.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
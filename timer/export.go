@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HistoryExporter writes history entries in the import format of another
+// timer app.
+type HistoryExporter interface {
+	Export(w io.Writer, entries []HistoryEntry) error
+}
+
+// HistoryExporterFunc lets a plain function be used as a HistoryExporter.
+type HistoryExporterFunc func(w io.Writer, entries []HistoryEntry) error
+
+func (f HistoryExporterFunc) Export(w io.Writer, entries []HistoryEntry) error {
+	return f(w, entries)
+}
+
+// exporters are the formats accepted by export --format.
+var exporters = map[string]HistoryExporter{
+	"toggl": HistoryExporterFunc(exportToggl),
+}
+
+// exportToggl writes entries as a CSV file Toggl Track can import: the
+// task name is the description, and each entry ends when it was recorded
+// and starts its duration earlier. The email and client columns are left
+// empty.
+func exportToggl(w io.Writer, entries []HistoryEntry) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"Email", "Project", "Client", "Description", "Start date", "Start time", "End date", "End time", "Duration", "Tags"})
+	for _, entry := range entries {
+		start := entry.CompletedAt.Add(-entry.Duration)
+		seconds := int(entry.Duration.Round(time.Second).Seconds())
+		out.Write([]string{
+			"", entry.Project, "",
+			entry.Name,
+			start.Format("2006-01-02"),
+			start.Format("15:04:05"),
+			entry.CompletedAt.Format("2006-01-02"),
+			entry.CompletedAt.Format("15:04:05"),
+			fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60),
+			strings.Join(entry.Tags, ", "),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// exportHistory handles "timer export --format <app> [--out <file>]",
+// writing the history in another app's import format to the file or, by
+// default, to stdout.
+func exportHistory(t *Timer, args []string) error {
+	format, args, _, err := takeOption(args, "format")
+	var outPath string
+	if err == nil {
+		outPath, args, _, err = takeOption(args, "out")
+	}
+	if err != nil {
+		return err
+	}
+	if format == "" || len(args) != 0 {
+		return errors.New("usage: timer export --format toggl [--out <file.csv>]")
+	}
+	exporter, ok := exporters[strings.ToLower(format)]
+	if !ok {
+		names := make([]string, 0, len(exporters))
+		for name := range exporters {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown export format %q (want %s)", format, strings.Join(names, " or "))
+	}
+
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	if outPath == "" {
+		return exporter.Export(os.Stdout, entries)
+	}
+
+	file, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	if err := exporter.Export(file, entries); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d entries to %s\n", len(entries), outPath)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+)
+
+const defaultExtremes = 10
+
+// extremesCommand handles "timer longest [--n <count>]" and "timer
+// shortest [--n <count>]", listing the completed tasks that ran longest
+// or shortest.
+func extremesCommand(t *Timer, args []string, longest bool) error {
+	value, args, hasN, err := takeOption(args, "n")
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("usage: timer longest|shortest [--n <count>]")
+	}
+	n := defaultExtremes
+	if hasN {
+		if n, err = strconv.Atoi(value); err != nil || n < 1 {
+			return fmt.Errorf("--n needs a positive count, got %q", value)
+		}
+	}
+
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	showExtremes(entries, n, longest)
+	return nil
+}
+
+func showExtremes(entries []HistoryEntry, n int, longest bool) {
+	var completed []HistoryEntry
+	for _, e := range entries {
+		if e.Status == StatusCompleted {
+			completed = append(completed, e)
+		}
+	}
+	if len(completed) == 0 {
+		fmt.Println("No completed tasks yet")
+		return
+	}
+
+	// Ties keep their order in the history.
+	slices.SortStableFunc(completed, func(a, b HistoryEntry) int {
+		if longest {
+			return cmp.Compare(b.Duration, a.Duration)
+		}
+		return cmp.Compare(a.Duration, b.Duration)
+	})
+	completed = completed[:min(n, len(completed))]
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTask\tDuration\tDate")
+	for i, e := range completed {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, e.Name, e.Duration, e.CompletedAt.Format(historyTimeLayout))
+	}
+	w.Flush()
+}
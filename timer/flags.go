@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// shorthands maps the single-letter forms of the most used flags to the
+// long flags they stand for.
+var shorthands = map[string]string{
+	"H": "history",
+	"S": "stats",
+	"f": "format",
+	"s": "sort",
+	"t": "tag",
+	"q": "quiet",
+	"v": "verbose",
+	"l": "log-level",
+	"p": "pomodoro",
+	"n": "no-notify",
+	"P": "parallel",
+	"o": "output",
+	"c": "config",
+	"d": "daemon",
+	"T": "task-file",
+}
+
+// defineShorthands registers each shorthand in fs as another name for
+// its long flag, sharing the flag's value.
+func defineShorthands(fs *flag.FlagSet) {
+	for short, long := range shorthands {
+		f := fs.Lookup(long)
+		fs.Var(f.Value, short, "Short for --"+long)
+	}
+}
+
+// isBoolFlag reports whether the flag called name in fs takes no value.
+func isBoolFlag(fs *flag.FlagSet, name string) bool {
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// expandShortFlags splits grouped shorthands such as -qn into -q -n, as
+// POSIX tools allow, so the flag package can parse them. The last letter
+// of a group may be a flag taking a value, as in -qf json. Arguments
+// after the flags, such as the command given to --ctl, are left alone.
+func expandShortFlags(fs *flag.FlagSet, args []string) []string {
+	var expanded []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return append(expanded, args[i:]...)
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "--") && len(name) > 1 && fs.Lookup(name) == nil && !hasValue && isShorthandGroup(fs, name) {
+			for _, r := range name {
+				expanded = append(expanded, "-"+string(r))
+			}
+			name = name[len(name)-1:]
+		} else {
+			expanded = append(expanded, arg)
+		}
+
+		// A flag's value is taken from the next argument unless it was
+		// given after "=".
+		if !hasValue && !isBoolFlag(fs, name) && fs.Lookup(name) != nil && i+1 < len(args) {
+			i++
+			expanded = append(expanded, args[i])
+		}
+	}
+	return expanded
+}
+
+// isShorthandGroup reports whether every letter of group is a shorthand
+// and all but the last take no value.
+func isShorthandGroup(fs *flag.FlagSet, group string) bool {
+	for i, r := range group {
+		long, ok := shorthands[string(r)]
+		if !ok || (i < len(group)-1 && !isBoolFlag(fs, long)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Forecast estimates when the running and pending tasks will all have
+// finished if nothing is added, paused or skipped, allowing for repeats,
+// start times and Config.Parallel. In Pomodoro mode the queue runs dry
+// into the break after a work session, so that break is included and
+// returned as well.
+func (t *Timer) Forecast(now time.Time) (time.Time, time.Duration, error) {
+	if t.Config.CountUp {
+		return time.Time{}, 0, errors.New("tasks counting up have no end to forecast")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// free holds when each slot is next free to start a task.
+	free := make([]time.Time, t.parallel())
+	for i := range free {
+		free[i] = now
+	}
+	// run places a task in the slot that frees up first.
+	run := func(d time.Duration, startAt time.Time) {
+		i := 0
+		for j := range free {
+			if free[j].Before(free[i]) {
+				i = j
+			}
+		}
+		start := free[i]
+		if startAt.After(start) {
+			start = startAt
+		}
+		free[i] = start.Add(d)
+	}
+
+	for i, active := range t.active {
+		if active.task.RepeatForever {
+			return time.Time{}, 0, fmt.Errorf("%s repeats forever", active.task.Name)
+		}
+		// A task waiting for its start time has yet to begin counting.
+		start := now
+		if active.task.StartAt.After(now) {
+			start = active.task.StartAt
+		}
+		free[i] = start.Add(max(active.task.Remaining, 0))
+	}
+	// Repeats follow on straight away, whatever the task's start time.
+	for _, active := range t.active {
+		for range active.task.RepeatCount - 1 {
+			run(active.task.Duration, time.Time{})
+		}
+	}
+	for _, task := range t.queue {
+		if task.RepeatForever {
+			return time.Time{}, 0, fmt.Errorf("%s repeats forever", task.Name)
+		}
+		run(task.Duration, task.StartAt)
+		for range task.RepeatCount - 1 {
+			run(task.Duration, time.Time{})
+		}
+	}
+
+	end := slices.MaxFunc(free, time.Time.Compare)
+	var pause time.Duration
+	if p := t.Config.Pomodoro; p != nil && p.step%2 == 1 {
+		pause = p.peek().Duration
+		end = end.Add(pause)
+	}
+	return end, pause, nil
+}
+
+// showForecast prints when the queue is expected to finish.
+func showForecast(t *Timer) error {
+	if len(t.Running()) == 0 && len(t.Queue()) == 0 {
+		fmt.Println("Queue is empty")
+		return nil
+	}
+
+	now := time.Now()
+	end, pause, err := t.Forecast(now)
+	if err != nil {
+		return fmt.Errorf("cannot forecast: %w", err)
+	}
+	layout := time.TimeOnly
+	if y, m, d := end.Date(); y != now.Year() || m != now.Month() || d != now.Day() {
+		layout = time.DateTime
+	}
+	fmt.Printf("Queue will complete at approximately %s (in %s)\n", end.Format(layout), spelledDuration(end.Sub(now)))
+	if pause > 0 {
+		fmt.Printf("This includes the %s Pomodoro break that follows.\n", shortDuration(pause))
+	}
+	return nil
+}
+
+// spelledDuration writes d to the second as in "1h 5m 30s", leaving out
+// leading units that are zero.
+func spelledDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d/time.Hour), int(d/time.Minute%60), int(d/time.Second%60)
+	var parts []string
+	if h > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", h))
+	}
+	if h > 0 || m > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", m))
+	}
+	parts = append(parts, fmt.Sprintf("%ds", s))
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Goal periods, the keys of goals.json.
+const (
+	goalDaily  = "daily"
+	goalWeekly = "weekly"
+)
+
+const goalBarWidth = 30
+
+func goalsPath() string {
+	return filepath.Join(configDir(), "goals.json")
+}
+
+// loadGoals reads the goals saved by saveGoals, a map of period to
+// duration such as {"daily": "4h"}. A missing file sets no goals.
+func loadGoals(path string) (map[string]time.Duration, error) {
+	goals := make(map[string]time.Duration)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return goals, nil
+		}
+		return nil, err
+	}
+	var saved map[string]string
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for period, value := range saved {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s goal: %w", period, err)
+		}
+		goals[period] = d
+	}
+	return goals, nil
+}
+
+func saveGoals(path string, goals map[string]time.Duration) error {
+	saved := make(map[string]string, len(goals))
+	for period, d := range goals {
+		saved[period] = shortDuration(d)
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// goalCommand handles "timer goal set [--daily <duration>] [--weekly
+// <duration>]", where a duration of 0 removes the goal, and "timer goal
+// status [--week]".
+func goalCommand(t *Timer, args []string) error {
+	const usage = "usage: timer goal set [--daily <duration>] [--weekly <duration>] | timer goal status [--week]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+	goals, err := loadGoals(goalsPath())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "set":
+		args = args[1:]
+		changed := false
+		for _, period := range []string{goalDaily, goalWeekly} {
+			var value string
+			var given bool
+			value, args, given, err = takeOption(args, period)
+			if err != nil {
+				return err
+			}
+			if !given {
+				continue
+			}
+			d, err := parseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid --%s: %w", period, err)
+			}
+			if d <= 0 {
+				delete(goals, period)
+			} else {
+				goals[period] = d
+			}
+			changed = true
+		}
+		if !changed || len(args) != 0 {
+			return errors.New(usage)
+		}
+		if err := saveGoals(goalsPath(), goals); err != nil {
+			return err
+		}
+		showGoals(goals)
+		return nil
+
+	case "status":
+		week, args := takeBoolOption(args[1:], "week")
+		if len(args) != 0 {
+			return errors.New(usage)
+		}
+		period := goalDaily
+		if week {
+			period = goalWeekly
+		}
+		goal, ok := goals[period]
+		if !ok {
+			fmt.Printf("No %s goal; set one with: timer goal set --%s <duration>\n", period, period)
+			return nil
+		}
+		entries, err := t.History()
+		if err != nil {
+			return err
+		}
+		spent := goalProgress(entries, period, time.Now())
+		label := "Today"
+		if week {
+			label = "This week"
+		}
+		fmt.Printf("%s: %s\n", label, goalLine(spent, goal))
+		return nil
+	}
+	return errors.New(usage)
+}
+
+func showGoals(goals map[string]time.Duration) {
+	if len(goals) == 0 {
+		fmt.Println("No goals")
+		return
+	}
+	for _, period := range []string{goalDaily, goalWeekly} {
+		if d, ok := goals[period]; ok {
+			fmt.Printf("%s goal: %s\n", strings.ToUpper(period[:1])+period[1:], shortDuration(d))
+		}
+	}
+}
+
+// goalProgress adds up the time recorded in the day or week, by period,
+// holding now.
+func goalProgress(entries []HistoryEntry, period string, now time.Time) time.Duration {
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if period == goalWeekly {
+		start = startOfWeek(now)
+	}
+	var spent time.Duration
+	for _, e := range entries {
+		if !e.CompletedAt.Before(start) && !e.CompletedAt.After(now) {
+			spent += e.Duration
+		}
+	}
+	return spent
+}
+
+// goalLine shows spent against goal, as in "3h15m / 4h (81%)", with a
+// progress bar.
+func goalLine(spent, goal time.Duration) string {
+	done := min(float64(spent)/float64(goal), 1)
+	filled := int(done * goalBarWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", goalBarWidth-filled)
+	if spent >= goal {
+		bar = colorize(colors.Completed, bar)
+	}
+	precision := time.Minute
+	if spent < time.Minute {
+		precision = time.Second
+	}
+	return fmt.Sprintf("%s / %s (%d%%) [%s]",
+		shortDuration(spent.Round(precision)), shortDuration(goal), int(float64(spent)/float64(goal)*100), bar)
+}
+
+// checkGoals notifies when entry, just written to the history, takes the
+// time recorded today or this week up to its goal.
+func checkGoals(t *Timer, goals map[string]time.Duration, entry HistoryEntry) {
+	if len(goals) == 0 {
+		return
+	}
+	entries, err := t.History()
+	if err != nil {
+		slog.Warn("cannot check goals", "err", err)
+		return
+	}
+	for _, period := range []string{goalDaily, goalWeekly} {
+		goal, ok := goals[period]
+		if !ok {
+			continue
+		}
+		spent := goalProgress(entries, period, entry.CompletedAt)
+		if spent >= goal && spent-entry.Duration < goal {
+			slog.Info("goal reached", "period", period, "goal", shortDuration(goal))
+			notifyGoalReached(period, goal)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	heatmapCell = 10 // side of a day's square in pixels
+	heatmapGap  = 2
+)
+
+// heatmapColor bins a day's completed tasks: none, 1-2, 3-5 and 6+.
+func heatmapColor(n int) string {
+	switch {
+	case n == 0:
+		return "#ebedf0"
+	case n <= 2:
+		return "#9be9a8"
+	case n <= 5:
+		return "#40c463"
+	default:
+		return "#216e39"
+	}
+}
+
+// writeHeatmap renders the tasks completed on each day of year as an SVG
+// calendar like GitHub's contribution graph: a column per week and a row
+// per weekday, starting on Sunday.
+func writeHeatmap(w io.Writer, entries []HistoryEntry, year int) error {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		if e.Status == StatusCompleted && e.CompletedAt.Year() == year {
+			counts[e.CompletedAt.Format(time.DateOnly)]++
+		}
+	}
+
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	last := time.Date(year, time.December, 31, 0, 0, 0, 0, time.Local)
+	offset := int(first.Weekday())
+	weeks := (offset+last.YearDay()-1)/7 + 1
+	step := heatmapCell + heatmapGap
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`+"\n",
+		weeks*step+heatmapGap, 7*step+heatmapGap)
+	for d := first; d.Year() == year; d = d.AddDate(0, 0, 1) {
+		i := offset + d.YearDay() - 1
+		key := d.Format(time.DateOnly)
+		fmt.Fprintf(bw, `  <rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s: %d task(s)</title></rect>`+"\n",
+			heatmapGap+i/7*step, heatmapGap+i%7*step, heatmapCell, heatmapCell,
+			heatmapColor(counts[key]), key, counts[key])
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
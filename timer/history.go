@@ -0,0 +1,489 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultHistoryFile = "timer_history.log"
+	historyFileEnv     = "TIMER_HISTORY_FILE"
+	historyTimeLayout  = "2006-01-02 15:04:05"
+)
+
+// History file formats accepted by --log-format. Reading detects the
+// format of each line, so a file may mix both.
+const (
+	LogFormatPipe  = "pipe"
+	LogFormatJSONL = "jsonl"
+)
+
+// Statuses recorded in the history file.
+const (
+	StatusCompleted = "completed"
+	StatusSkipped   = "skipped"
+	// StatusInterrupted is a task still running when the process was
+	// told to end. Its duration is the time it ran.
+	StatusInterrupted = "interrupted"
+)
+
+// HistoryEntry is a single task recorded in the history file. Duration is
+// the time actually spent, so for skipped tasks it is the elapsed time.
+type HistoryEntry struct {
+	Name        string        `json:"name"`
+	Duration    time.Duration `json:"duration"`
+	CompletedAt time.Time     `json:"completedAt"`
+	Status      string        `json:"status"`
+	Tags        []string      `json:"tags,omitempty"`
+	Project     string        `json:"project,omitempty"`
+	Notes       string        `json:"notes,omitempty"`
+}
+
+// MarshalJSON writes Duration in its human readable form ("25m0s")
+// instead of nanoseconds.
+func (e HistoryEntry) MarshalJSON() ([]byte, error) {
+	type entry HistoryEntry
+	return json.Marshal(struct {
+		entry
+		Duration string `json:"duration"`
+	}{entry(e), e.Duration.String()})
+}
+
+// historyRecord is one line of a JSON Lines history file.
+type historyRecord struct {
+	Name      string   `json:"name"`
+	Duration  string   `json:"duration"`
+	Completed string   `json:"completed"`
+	Status    string   `json:"status"`
+	Tags      []string `json:"tags,omitempty"`
+	Project   string   `json:"project,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+}
+
+func validateLogFormat(format string) error {
+	switch format {
+	case "", LogFormatPipe, LogFormatJSONL:
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q (want pipe or jsonl)", format)
+}
+
+// logHistory appends entry to config.HistoryFile, either as
+// name|duration|time|status|tags, with the tags separated by commas, any
+// notes added as a sixth, Go-quoted field and any project as a seventh,
+// or, for LogFormatJSONL, as a JSON object. The file is rotated first if
+// it has grown too large.
+func logHistory(config Config, entry HistoryEntry) error {
+	path := config.HistoryFile
+	line := fmt.Sprintf("%s|%s|%s|%s|%s",
+		entry.Name,
+		entry.Duration.String(),
+		entry.CompletedAt.Format(historyTimeLayout),
+		entry.Status,
+		strings.Join(entry.Tags, ","),
+	)
+	if entry.Notes != "" || entry.Project != "" {
+		line += "|" + strconv.Quote(entry.Notes)
+	}
+	if entry.Project != "" {
+		line += "|" + entry.Project
+	}
+	line += "\n"
+	if config.LogFormat == LogFormatJSONL {
+		data, err := json.Marshal(historyRecord{
+			Name:      entry.Name,
+			Duration:  entry.Duration.String(),
+			Completed: entry.CompletedAt.Format(time.RFC3339),
+			Status:    entry.Status,
+			Tags:      entry.Tags,
+			Project:   entry.Project,
+			Notes:     entry.Notes,
+		})
+		if err != nil {
+			return err
+		}
+		line = string(data) + "\n"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := rotateIfNeeded(path, config.MaxLogSize, config.MaxLogBackups); err != nil {
+		return fmt.Errorf("rotating %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if verbose {
+		slog.Debug("writing history", "file", path, "bytes", line)
+	}
+	_, err = file.WriteString(line)
+	return err
+}
+
+// archiveHistory renames the history file at path to
+// <name>_<timestamp><ext>, and any rotated backups to match, then creates
+// a new empty history file. It returns the archive's path.
+func archiveHistory(path string, now time.Time) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", errors.New("there is no history to archive")
+		}
+		return "", err
+	}
+
+	ext := filepath.Ext(path)
+	archive := strings.TrimSuffix(path, ext) + "_" + now.Format("20060102-150405") + ext
+	if _, err := os.Stat(archive); err == nil {
+		return "", fmt.Errorf("%s already exists", archive)
+	}
+
+	if err := os.Rename(path, archive); err != nil {
+		return "", err
+	}
+	for n := 1; ; n++ {
+		err := os.Rename(backupName(path, n), backupName(archive, n))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return archive, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return archive, err
+	}
+	return archive, file.Close()
+}
+
+// readHistory parses every well-formed line of the history file and its
+// rotated backups, oldest first. Lines that cannot be parsed are skipped.
+// A missing file yields no entries.
+func readHistory(path string) ([]HistoryEntry, error) {
+	n := 0
+	for {
+		if _, err := os.Stat(backupName(path, n+1)); err != nil {
+			break
+		}
+		n++
+	}
+
+	var entries []HistoryEntry
+	for ; n >= 0; n-- {
+		name := path
+		if n > 0 {
+			name = backupName(path, n)
+		}
+		fileEntries, err := readHistoryFile(name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+func readHistoryFile(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry, ok := parseHistoryLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// parseHistoryLine accepts JSON Lines records, the current five-field
+// format, optionally followed by notes and then a project (after empty
+// quoted notes if there are none), and the older lines it grew from:
+// name|duration|time|status lines, which predate tags, and
+// name|duration|time lines, which predate statuses and are always
+// completed tasks. Old files are read as they are, without being
+// rewritten.
+func parseHistoryLine(line string) (HistoryEntry, bool) {
+	if strings.HasPrefix(line, "{") {
+		return parseHistoryRecord(line)
+	}
+
+	// Notes may contain pipes of their own, so they and the project after
+	// them are split by unquoting the notes.
+	parts := strings.SplitN(line, "|", 6)
+	if len(parts) < 3 {
+		return HistoryEntry{}, false
+	}
+
+	status := StatusCompleted
+	if len(parts) >= 4 && parts[3] != "" {
+		status = parts[3]
+	}
+	var tags []string
+	if len(parts) >= 5 && parts[4] != "" {
+		tags = strings.Split(parts[4], ",")
+	}
+	var notes, project string
+	if len(parts) == 6 {
+		quoted, err := strconv.QuotedPrefix(parts[5])
+		if err != nil {
+			return HistoryEntry{}, false
+		}
+		notes, _ = strconv.Unquote(quoted)
+		rest := parts[5][len(quoted):]
+		if rest != "" && !strings.HasPrefix(rest, "|") {
+			return HistoryEntry{}, false
+		}
+		project = strings.TrimPrefix(rest, "|")
+	}
+
+	duration, err := time.ParseDuration(parts[1])
+	if err != nil {
+		return HistoryEntry{}, false
+	}
+	completedAt, err := time.ParseInLocation(historyTimeLayout, parts[2], time.Local)
+	if err != nil {
+		return HistoryEntry{}, false
+	}
+
+	return HistoryEntry{Name: parts[0], Duration: duration, CompletedAt: completedAt, Status: status, Tags: tags, Project: project, Notes: notes}, true
+}
+
+func parseHistoryRecord(line string) (HistoryEntry, bool) {
+	var record historyRecord
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return HistoryEntry{}, false
+	}
+
+	duration, err := time.ParseDuration(record.Duration)
+	if err != nil {
+		return HistoryEntry{}, false
+	}
+	completedAt, err := time.Parse(time.RFC3339, record.Completed)
+	if err != nil {
+		return HistoryEntry{}, false
+	}
+	if record.Status == "" {
+		record.Status = StatusCompleted
+	}
+
+	return HistoryEntry{
+		Name:        record.Name,
+		Duration:    duration,
+		CompletedAt: completedAt.Local(),
+		Status:      record.Status,
+		Tags:        record.Tags,
+		Project:     record.Project,
+		Notes:       record.Notes,
+	}, true
+}
+
+func showHistory(entries []HistoryEntry, format string) error {
+	switch format {
+	case "text":
+		return writeHistoryText(entries)
+	case "json":
+		return writeHistoryJSON(entries)
+	case "csv":
+		return writeHistoryCSV(entries)
+	case "markdown":
+		return writeHistoryMarkdown(entries)
+	default:
+		return fmt.Errorf("unknown format %q (want text, json, csv or markdown)", format)
+	}
+}
+
+// filterHistory keeps the entries completed within [from, to], both
+// whole days, and tagged with any of tags. A zero time leaves that end of
+// the range open and no tags match every entry.
+func filterHistory(entries []HistoryEntry, from, to time.Time, tags []string) []HistoryEntry {
+	var filtered []HistoryEntry
+	for _, e := range entries {
+		if !hasAnyTag(e.Tags, tags) {
+			continue
+		}
+		if !from.IsZero() && e.CompletedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !e.CompletedAt.Before(to.AddDate(0, 0, 1)) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
+// searchHistory keeps the entries whose name matches: contains the
+// term, ignoring case, or, if it is non-nil, matches the regular
+// expression.
+func searchHistory(entries []HistoryEntry, term string, re *regexp.Regexp) []HistoryEntry {
+	var found []HistoryEntry
+	for _, e := range entries {
+		if re != nil && re.MatchString(e.Name) ||
+			re == nil && strings.Contains(strings.ToLower(e.Name), strings.ToLower(term)) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// parseDate reads a YYYY-MM-DD date in local time. An empty string gives
+// the zero time.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", value)
+	}
+	return date, nil
+}
+
+// sortHistory orders entries in place by name, date or duration. An
+// empty key keeps the file order.
+func sortHistory(entries []HistoryEntry, key string) error {
+	var less func(a, b HistoryEntry) bool
+	switch key {
+	case "":
+		return nil
+	case "name":
+		less = func(a, b HistoryEntry) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "date":
+		less = func(a, b HistoryEntry) bool { return a.CompletedAt.Before(b.CompletedAt) }
+	case "duration":
+		less = func(a, b HistoryEntry) bool { return a.Duration < b.Duration }
+	default:
+		return fmt.Errorf("unknown sort key %q (want name, date or duration)", key)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i], entries[j])
+	})
+	return nil
+}
+
+func writeHistoryText(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		fmt.Println("No history available")
+		return nil
+	}
+
+	fmt.Println("\nTask History:")
+	fmt.Println("----------------------------------------")
+	for _, e := range entries {
+		var details string
+		if e.Project != "" {
+			details = "Project: " + e.Project + "\n"
+		}
+		if len(e.Tags) > 0 {
+			details += "Tags: " + strings.Join(e.Tags, ", ") + "\n"
+		}
+		if e.Notes != "" {
+			details += "Notes:\n    " + strings.ReplaceAll(e.Notes, "\n", "\n    ") + "\n"
+		}
+		if e.Status != StatusCompleted {
+			fmt.Printf("Task: %s\nDuration: %s (%s)\n%s: %s\n%s\n",
+				e.Name, e.Duration, colorize(colors.Warning, e.Status),
+				strings.ToUpper(e.Status[:1])+e.Status[1:], e.CompletedAt.Format(historyTimeLayout), details)
+			continue
+		}
+		fmt.Printf("Task: %s\nDuration: %s\nCompleted: %s\n%s\n",
+			e.Name, e.Duration, e.CompletedAt.Format(historyTimeLayout), details)
+	}
+	return nil
+}
+
+func writeHistoryJSON(entries []HistoryEntry) error {
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+func writeHistoryCSV(entries []HistoryEntry) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"name", "duration", "completed_at", "status", "tags", "notes", "project"})
+	for _, e := range entries {
+		w.Write([]string{e.Name, e.Duration.String(), e.CompletedAt.Format(historyTimeLayout), e.Status, strings.Join(e.Tags, ","), e.Notes, e.Project})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeHistoryMarkdown renders a GitHub flavoured Markdown table with the
+// columns padded so the pipes line up.
+func writeHistoryMarkdown(entries []HistoryEntry) error {
+	rows := [][]string{{"Task", "Duration", "Completed", "Tags", "Notes", "Project"}}
+	for _, e := range entries {
+		duration := e.Duration.String()
+		if e.Status != StatusCompleted {
+			duration += " (" + e.Status + ")"
+		}
+		rows = append(rows, []string{
+			strings.ReplaceAll(e.Name, "|", "\\|"),
+			duration,
+			e.CompletedAt.Format(historyTimeLayout),
+			strings.ReplaceAll(strings.Join(e.Tags, ", "), "|", "\\|"),
+			strings.NewReplacer("|", "\\|", "\n", "<br>").Replace(e.Notes),
+			e.Project,
+		})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	writeRow := func(cells []string) {
+		var b strings.Builder
+		b.WriteString("|")
+		for i, cell := range cells {
+			b.WriteString(" " + cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)) + " |")
+		}
+		fmt.Println(b.String())
+	}
+
+	writeRow(rows[0])
+	separator := make([]string, len(widths))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	writeRow(separator)
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return nil
+}
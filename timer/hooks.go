@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var (
+	// afterAll is the --after-all shell command, run once the queue
+	// empties.
+	afterAll string
+	// afterEach is the --after-each shell command, run after every
+	// completed task with {task} and {duration} standing for the task's
+	// name and duration.
+	afterEach string
+)
+
+// runAfterAll runs the --after-all command, or only shows it in a dry run.
+func runAfterAll() {
+	if afterAll == "" {
+		return
+	}
+	if dryRun {
+		fmt.Printf("Would run after the queue empties: %s\n", afterAll)
+		return
+	}
+
+	fmt.Printf("Running: %s\n", afterAll)
+	cmd := exec.Command("sh", "-c", afterAll)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		slog.Warn("--after-all command failed", "command", afterAll, "err", err)
+	}
+}
+
+// runAfterEach runs the --after-each command for task in the background.
+// Its stderr is logged if it fails; the timer carries on either way.
+func runAfterEach(task Task) {
+	if afterEach == "" {
+		return
+	}
+
+	cmd := afterEachCommand(task)
+	go func() {
+		var stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = os.Stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			slog.Warn("--after-each command failed", "task", task.Name, "err", err,
+				"stderr", strings.TrimSpace(stderr.String()))
+		}
+	}()
+}
+
+// afterEachCommand prepares the --after-each command for task. Task names
+// come from the network and from files as well as the prompt, so {task}
+// and {duration} are passed in the environment as TIMER_TASK and
+// TIMER_DURATION and never spliced into the script itself.
+func afterEachCommand(task Task) *exec.Cmd {
+	script := strings.NewReplacer(
+		"{task}", `"$TIMER_TASK"`,
+		"{duration}", `"$TIMER_DURATION"`,
+	).Replace(afterEach)
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Env = append(os.Environ(),
+		"TIMER_TASK="+task.Name,
+		"TIMER_DURATION="+shortDuration(task.Duration.Round(time.Second)),
+	)
+	return cmd
+}
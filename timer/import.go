@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryImporter reads the time entries exported by another timer app
+// as history entries.
+type HistoryImporter interface {
+	Import(r io.Reader) ([]HistoryEntry, error)
+}
+
+// HistoryImporterFunc lets a plain function be used as a HistoryImporter.
+type HistoryImporterFunc func(r io.Reader) ([]HistoryEntry, error)
+
+func (f HistoryImporterFunc) Import(r io.Reader) ([]HistoryEntry, error) {
+	return f(r)
+}
+
+// importers are the formats accepted by import --format.
+var importers = map[string]HistoryImporter{
+	"toggl":    HistoryImporterFunc(importToggl),
+	"clockify": HistoryImporterFunc(importClockify),
+}
+
+// importToggl reads a Toggl Track detailed CSV export, with columns
+// including Description, Start date, Start time, End date, End time and
+// Duration. Entries without a description are named after their project.
+func importToggl(r io.Reader) ([]HistoryEntry, error) {
+	return importCSV(r, csvLayout{
+		name: "description", project: "project", tags: "tags",
+		startDate: "start date", startTime: "start time",
+		endDate: "end date", endTime: "end time",
+		duration: "duration",
+	})
+}
+
+// importClockify reads a Clockify detailed CSV report, with columns
+// including Description, Start Date, Start Time, End Date, End Time and
+// Duration (h). Clockify writes dates and times in the account's
+// format, so both US and ISO dates and 12 and 24 hour times are read.
+func importClockify(r io.Reader) ([]HistoryEntry, error) {
+	return importCSV(r, csvLayout{
+		name: "description", project: "project", tags: "tags",
+		startDate: "start date", startTime: "start time",
+		endDate: "end date", endTime: "end time",
+		duration: "duration (h)",
+	})
+}
+
+// csvLayout names, in lower case, the columns an export keeps each
+// field in. Columns other than the end date and time may be missing.
+type csvLayout struct {
+	name, project, tags  string
+	startDate, startTime string
+	endDate, endTime     string
+	duration             string
+}
+
+// importDateLayouts and importTimeLayouts are the date and time formats
+// the exports are known to use.
+var (
+	importDateLayouts = []string{"2006-01-02", "01/02/2006", "02.01.2006", "2006/01/02"}
+	importTimeLayouts = []string{"15:04:05", "03:04:05 PM", "3:04:05 PM", "15:04", "03:04 PM", "3:04 PM"}
+)
+
+func importCSV(r io.Reader, layout csvLayout) ([]HistoryEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("the file is empty")
+		}
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		// Exports from Excel start with a byte order mark.
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{layout.endDate, layout.endTime} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("no %q column; is this the right --format?", required)
+		}
+	}
+
+	var entries []HistoryEntry
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		end, err := parseImportTime(field(layout.endDate), field(layout.endTime))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: end: %w", row, err)
+		}
+		duration, err := parseClockDuration(field(layout.duration))
+		if err != nil {
+			// Without a usable duration column, the entry lasted from
+			// its start to its end.
+			start, startErr := parseImportTime(field(layout.startDate), field(layout.startTime))
+			if startErr != nil {
+				return nil, fmt.Errorf("row %d: duration: %w", row, err)
+			}
+			duration = end.Sub(start)
+		}
+
+		name := field(layout.name)
+		if name == "" {
+			name = field(layout.project)
+		}
+		if name == "" {
+			name = "(no description)"
+		}
+		var tags []string
+		for _, tag := range strings.Split(field(layout.tags), ",") {
+			if tag = cleanImported(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		entries = append(entries, HistoryEntry{
+			Name:        cleanImported(name),
+			Duration:    duration,
+			CompletedAt: end,
+			Status:      StatusCompleted,
+			Tags:        tags,
+			Project:     cleanImported(field(layout.project)),
+		})
+	}
+	return entries, nil
+}
+
+// cleanImported makes an imported name or tag safe for the pipe
+// separated history format.
+func cleanImported(s string) string {
+	return strings.TrimSpace(strings.NewReplacer("|", "/", "\r", " ", "\n", " ").Replace(s))
+}
+
+// parseImportTime reads a local date and time in any of the known
+// layouts.
+func parseImportTime(date, clock string) (time.Time, error) {
+	for _, dateLayout := range importDateLayouts {
+		for _, timeLayout := range importTimeLayouts {
+			if t, err := time.ParseInLocation(dateLayout+" "+timeLayout, date+" "+clock, time.Local); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot read %q as a date and time", date+" "+clock)
+}
+
+// parseClockDuration reads a duration written as H:MM:SS, or as decimal
+// hours such as 1.50.
+func parseClockDuration(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 3 {
+		var total time.Duration
+		for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+			n, err := strconv.Atoi(parts[i])
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			total += time.Duration(n) * unit
+		}
+		return total, nil
+	}
+	hours, err := strconv.ParseFloat(s, 64)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(hours * float64(time.Hour)).Round(time.Second), nil
+}
+
+// importHistory handles "timer import --format <app> <file>", appending
+// the entries of another app's export to the history. Entries finishing
+// at the same second as one already in the history are taken to be the
+// same and skipped, so importing a file twice is harmless.
+func importHistory(t *Timer, args []string) error {
+	format, args, _, err := takeOption(args, "format")
+	if err != nil {
+		return err
+	}
+	if format == "" || len(args) != 1 {
+		return errors.New("usage: timer import --format toggl|clockify <file.csv>")
+	}
+	importer, ok := importers[strings.ToLower(format)]
+	if !ok {
+		names := make([]string, 0, len(importers))
+		for name := range importers {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown import format %q (want %s)", format, strings.Join(names, " or "))
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	entries, err := importer.Import(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	existing, err := t.History()
+	if err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(existing))
+	for _, entry := range existing {
+		seen[entry.CompletedAt.Unix()] = true
+	}
+
+	slices.SortStableFunc(entries, func(a, b HistoryEntry) int {
+		return a.CompletedAt.Compare(b.CompletedAt)
+	})
+	imported := 0
+	for _, entry := range entries {
+		if seen[entry.CompletedAt.Unix()] {
+			continue
+		}
+		if err := logHistory(t.Config, entry); err != nil {
+			return err
+		}
+		seen[entry.CompletedAt.Unix()] = true
+		imported++
+	}
+	fmt.Printf("Imported %d entries from %s into %s", imported, args[0], t.Config.HistoryFile)
+	if skipped := len(entries) - imported; skipped > 0 {
+		fmt.Printf("; skipped %d already in the history", skipped)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ipcRequest is one newline-delimited JSON command sent to the daemon's
+// socket, e.g. {"command":"add","args":["Study","25m"]}.
+type ipcRequest struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// ipcResponse answers a single ipcRequest.
+type ipcResponse struct {
+	Message string     `json:"message,omitempty"`
+	Tasks   []taskJSON `json:"tasks,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+func socketPath() string {
+	return filepath.Join(runDir(), "timer.sock")
+}
+
+// listenIPC serves commands on the daemon socket until the returned
+// listener is closed. A socket left behind by an earlier daemon is
+// replaced.
+func listenIPC(t *Timer) (net.Listener, error) {
+	if err := os.MkdirAll(runDir(), 0755); err != nil {
+		return nil, err
+	}
+	os.Remove(socketPath())
+	listener, err := net.Listen("unix", socketPath())
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveIPC(t, conn)
+		}
+	}()
+	return listener, nil
+}
+
+func serveIPC(t *Timer, conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req ipcRequest
+		var resp ipcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp = handleIPC(t, req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handleIPC runs one command against the timer. The commands mirror the
+// interactive ones; task numbers are the 1-based positions from list.
+func handleIPC(t *Timer, req ipcRequest) ipcResponse {
+	var resp ipcResponse
+	var err error
+	switch strings.ToLower(req.Command) {
+	case "add":
+		var task Task
+		task, err = parseTask(req.Args, !t.Config.CountUp)
+		if err == nil {
+			err = t.CheckTask(task)
+		}
+		if err == nil {
+			t.Add(task)
+			resp.Message = fmt.Sprintf("Added task: %s (%s%s)", task.Name, task.Duration.Round(time.Second), startString(task.StartAt))
+		}
+	case "list":
+		for _, task := range t.Running() {
+			resp.Tasks = append(resp.Tasks, newTaskJSON(0, task))
+		}
+		for i, task := range t.Queue() {
+			resp.Tasks = append(resp.Tasks, newTaskJSON(i+1, task))
+		}
+		if len(resp.Tasks) == 0 {
+			resp.Message = "Queue is empty"
+		}
+	case "remove":
+		var i int
+		var task Task
+		if len(req.Args) != 1 {
+			err = errors.New("usage: remove <n>")
+		} else if i, err = taskNumber(req.Args[0]); err == nil {
+			task, err = t.Remove(i)
+		}
+		if err == nil {
+			resp.Message = "Removed task: " + task.Name
+		}
+	case "clear":
+		resp.Message = fmt.Sprintf("Cleared %d pending task(s)", t.Clear())
+	case "pause":
+		err = t.Pause()
+		resp.Message = "Paused"
+	case "resume":
+		err = t.Resume()
+		resp.Message = "Resumed"
+	case "cancel":
+		err = t.Cancel()
+		resp.Message = "Cancelled"
+	case "skip":
+		err = t.Skip()
+		resp.Message = "Skipped"
+	case "done":
+		err = t.Done()
+		resp.Message = "Done"
+	case "extend", "shorten":
+		var d time.Duration
+		d, err = parseDuration(strings.Join(req.Args, " "))
+		if err == nil && d <= 0 {
+			err = errors.New("duration must be positive")
+		}
+		if err == nil && req.Command == "extend" {
+			err = t.Extend(d)
+			resp.Message = "Extended by " + d.Round(time.Second).String()
+		} else if err == nil {
+			err = t.Shorten(d)
+			resp.Message = "Shortened by " + d.Round(time.Second).String()
+		}
+	default:
+		err = fmt.Errorf("unknown command %q (want add, list, remove, clear, pause, resume, cancel, skip, done, extend or shorten)", req.Command)
+	}
+
+	if err != nil {
+		return ipcResponse{Error: err.Error()}
+	}
+	return resp
+}
+
+// runCtl sends a single command, given as command-line arguments, to the
+// daemon's socket and prints the response.
+func runCtl(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: timer --ctl <command> [args...]")
+	}
+
+	conn, err := net.Dial("unix", socketPath())
+	if err != nil {
+		return fmt.Errorf("cannot reach the timer daemon: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(ipcRequest{Command: args[0], Args: args[1:]}); err != nil {
+		return err
+	}
+	var resp ipcResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+
+	if len(resp.Tasks) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tTask\tPriority\tDuration\tRemaining")
+		for _, task := range resp.Tasks {
+			id := "[running]"
+			if task.ID > 0 {
+				id = fmt.Sprint(task.ID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, task.Name, task.Priority, task.Duration, task.Remaining)
+		}
+		w.Flush()
+	}
+	if resp.Message != "" {
+		fmt.Println(resp.Message)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// verbose, set by --verbose, adds the most detailed diagnostics to the
+// debug log: every lock and unlock of a debugMutex, every tick of a
+// countdown and the bytes written to the history file.
+var verbose bool
+
+// Encodings of the diagnostic log for --log-encoding.
+const (
+	LogEncodingText = "text"
+	LogEncodingJSON = "json"
+)
+
+// setupLogging points the default slog logger at w, keeping records of at
+// least level (debug, info, warn or error) in the given encoding.
+func setupLogging(w io.Writer, level, encoding string) error {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level '%s': use debug, info, warn or error", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: minLevel}
+	switch encoding {
+	case LogEncodingText:
+		slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	case LogEncodingJSON:
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, opts)))
+	default:
+		return fmt.Errorf("invalid log encoding '%s': use %s or %s", encoding, LogEncodingText, LogEncodingJSON)
+	}
+	return nil
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// stderrWriter writes to whatever os.Stderr is at the time, so the log
+// follows it into the full-screen interface.
+type stderrWriter struct{}
+
+func (stderrWriter) Write(p []byte) (int, error) {
+	return os.Stderr.Write(p)
+}
+
+// debugMutex is a sync.Mutex that logs its Lock and Unlock calls while
+// verbose is set, for tracking down contention and deadlocks.
+type debugMutex struct {
+	sync.Mutex
+	name string
+}
+
+func (m *debugMutex) Lock() {
+	if verbose {
+		slog.Debug("locking mutex", "mutex", m.name)
+	}
+	m.Mutex.Lock()
+	if verbose {
+		slog.Debug("locked mutex", "mutex", m.name)
+	}
+}
+
+func (m *debugMutex) Unlock() {
+	m.Mutex.Unlock()
+	if verbose {
+		slog.Debug("unlocked mutex", "mutex", m.name)
+	}
+}
@@ -2,228 +2,642 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 )
 
-type Task struct {
-	Name     string
-	Duration time.Duration
+func handleInput(cmdCh chan<- string) {
+	slog.Debug("input goroutine started")
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		cmdCh <- scanner.Text()
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Warn("cannot read stdin", "err", err)
+	}
+	close(cmdCh)
 }
 
-const historyFile = "timer_history.log"
+// stringList is a flag that may be given more than once.
+type stringList []string
 
-var (
-	taskQueue []Task
-	queueMux  sync.Mutex
-)
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-func parseDuration(input string) (time.Duration, error) {
-	fs := flag.NewFlagSet("durationFlags", flag.ContinueOnError)
-	h := fs.Int("h", 0, "Hours")
-	m := fs.Int("m", 0, "Minutes")
-	s := fs.Int("s", 0, "Seconds")
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-	args := strings.Fields(input)
-	if err := fs.Parse(args); err != nil {
-		return 0, err
-	}
+// percentList is a flag holding percentages from 1 to 99, kept sorted,
+// that may be given more than once.
+type percentList []int
 
-	if *h < 0 || *m < 0 || *s < 0 {
-		return 0, fmt.Errorf("negative values not allowed")
+func (l *percentList) String() string {
+	s := make([]string, len(*l))
+	for i, p := range *l {
+		s[i] = strconv.Itoa(p)
 	}
+	return strings.Join(s, ",")
+}
 
-	return time.Duration(*h)*time.Hour +
-		time.Duration(*m)*time.Minute +
-		time.Duration(*s)*time.Second, nil
+func (l *percentList) Set(value string) error {
+	p, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || p < 1 || p > 99 {
+		return fmt.Errorf("'%s' is not a percentage from 1 to 99", value)
+	}
+	if !slices.Contains(*l, p) {
+		*l = append(*l, p)
+		slices.Sort(*l)
+	}
+	return nil
 }
 
-func startTimer(task string, duration time.Duration) {
-	endTime := time.Now().Add(duration)
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+func main() {
+	historyFlag := flag.Bool("history", false, "Show timer history")
+	statsFlag := flag.Bool("stats", false, "Show aggregate statistics from the history")
+	heatmapFlag := flag.Int("heatmap", 0, "Write an SVG calendar heatmap of the tasks completed in this year")
+	heatmapOutFlag := flag.String("heatmap-out", "", "Write the --heatmap SVG to this file instead of stdout")
+	formatFlag := flag.String("format", "text", "History output format: text, json, csv or markdown")
+	sortFlag := flag.String("sort", "", "Sort history by name, date or duration")
+	fromFlag := flag.String("from", "", "Only include history from this date (YYYY-MM-DD)")
+	var tags stringList
+	flag.Var(&tags, "tag", "Only include history entries with this tag; repeat to allow several")
+	toFlag := flag.String("to", "", "Only include history up to this date (YYYY-MM-DD)")
+	logFormatFlag := flag.String("log-format", LogFormatPipe, "History log format for new entries: pipe or jsonl")
+	maxLogSize := byteSize(defaultMaxLogSize)
+	flag.Var(&maxLogSize, "max-log-size", "Rotate the history log once it reaches this size, e.g. 10MB (0 disables rotation)")
+	maxLogBackupsFlag := flag.Int("max-log-backups", defaultMaxLogBackups, "Number of rotated history logs to keep")
+	logLevelFlag := flag.String("log-level", "info", "Minimum level of diagnostic messages: debug, info, warn or error")
+	flag.BoolVar(&verbose, "verbose", false, "Log goroutines, locks, ticks and history writes at debug level; implies --log-level debug")
+	logEncodingFlag := flag.String("log-encoding", LogEncodingText, "Encoding of diagnostic messages on stderr: text or json")
+	timezoneFlag := flag.String("timezone", "", "Time zone for 'at HH:MM' start times and history timestamps, e.g. America/New_York (default local)")
+	syncHistoryFlag := flag.String("sync-history", "", "Also POST every new history entry as JSON to this URL")
+	historyFileFlag := flag.String("history-file", "", "History log path (default $"+historyFileEnv+" or "+defaultHistoryFile+")")
+	pomodoroFlag := flag.Bool("pomodoro", false, "Cycle work and break intervals automatically")
+	workFlag := flag.Duration("work", 25*time.Minute, "Pomodoro work duration")
+	shortBreakFlag := flag.Duration("short-break", 5*time.Minute, "Pomodoro short break duration")
+	longBreakFlag := flag.Duration("long-break", 15*time.Minute, "Pomodoro long break duration")
+	cyclesFlag := flag.Int("cycles", 4, "Pomodoro work sessions before a long break")
+	noNotifyFlag := flag.Bool("no-notify", false, "Disable desktop notifications")
+	colorSchemeFlag := flag.String("color-scheme", "default", "Colours to use: default, solarized, dracula, nord or a scheme from "+colorSchemesPath())
+	noTUIFlag := flag.Bool("no-tui", false, "Print progress line by line instead of using the full-screen interface")
+	noColorFlag := flag.Bool("no-color", false, "Disable coloured output (also disabled by setting NO_COLOR)")
+	flag.BoolVar(&soundEnabled, "sound", false, "Play a sound when a timer completes")
+	flag.StringVar(&alertSoundFile, "sound-file", "", "WAV/MP3 file to play with --sound (default: terminal bell)")
+	flag.BoolVar(&bellEnabled, "bell", false, "Ring the terminal bell when a timer completes")
+	flag.IntVar(&bellCount, "repeat-bell", 1, "Number of times --bell rings")
+	var beepAt percentList
+	flag.Var(&beepAt, "beep-at", "Beep and notify when this percentage of a countdown has elapsed; repeat for several")
+	maxTaskDurationFlag := flag.Duration("max-task-duration", 0, "Reject tasks longer than this, e.g. 8h (default unlimited)")
+	maxQueueDepthFlag := flag.Int("max-queue-depth", 0, "Reject new tasks once this many are pending, warning at 80% (default unlimited)")
+	sessionReportFlag := flag.Bool("session-report", false, "Print a summary of the session once the queue has run dry")
+	countUpFlag := flag.Bool("count-up", false, "Show the time spent on each task and finish it with the done command")
+	sortQueueFlag := flag.Bool("sort-queue", false, "Run the shortest task first within each priority")
+	parallelFlag := flag.Int("parallel", 1, "Maximum number of timers running at once")
+	tickIntervalFlag := flag.Duration("tick-interval", time.Second, "How often the countdown is redrawn, at least 100ms")
+	countdownStyleFlag := flag.String("countdown-style", CountdownText, "How the countdown is drawn: text, bar or spinner")
+	barWidthFlag := flag.Int("bar-width", defaultBarWidth, "Width of the --countdown-style bar progress bar in columns")
+	serveFlag := flag.String("serve", "", "Serve the HTTP API on this address, e.g. :8080")
+	metricsPortFlag := flag.Int("metrics-port", 0, "Serve Prometheus metrics at http://localhost:<port>/metrics")
+	daemonFlag := flag.Bool("daemon", false, "Run the timer in the background")
+	stopFlag := flag.Bool("stop", false, "Stop the timer started with --daemon")
+	ctlFlag := flag.Bool("ctl", false, "Send the remaining arguments as a command to the timer started with --daemon")
+	daemonChild := flag.Bool(daemonChildFlag, false, "Internal: marks the background process started by --daemon")
+	flag.StringVar(&afterAll, "after-all", "", "Run this shell command once the queue empties, e.g. 'systemctl suspend'")
+	flag.StringVar(&afterEach, "after-each", "", "Run this shell command after every completed task; {task} and {duration} stand for its name and duration")
+	var cronFlags stringList
+	flag.Var(&cronFlags, "cron", "Add a task at every time matching this cron spec, e.g. \"0 */25 * * * *\"; the task follows the spec or the flags; repeat for several")
+	flag.BoolVar(&dryRun, "dry-run", false, "Validate commands from stdin without starting timers or writing files")
+	outputFlag := flag.String("output", "", "Write timer progress to this file with timestamps instead of stdout")
+	quietFlag := flag.Bool("quiet", false, "Only print when tasks finish, hiding the countdown and start messages; errors are still shown")
+	timeoutFlag := flag.Duration("timeout", 0, "Exit once the queue has been empty for this long, e.g. 1h")
+	taskFileFlag := flag.String("task-file", "", "Queue the add commands, or YAML task lists for .yaml files, in the files matching this glob, e.g. 'sessions/*.timer', before reading stdin")
+	taskYAMLFlag := flag.String("task-yaml", "", "Queue the tasks of this inline YAML, e.g. '{name: Study, duration: 25m, tags: [focus]}'")
+	watchFileFlag := flag.String("watch-file", "", "Queue every line appended to this file as an add command")
+	profileFlag := flag.String("profile", "", "Write a CPU profile to this file, and a goroutine dump to <file>.goroutines at exit")
+	memProfileFlag := flag.String("memprofile", "", "Write a heap profile to this file at exit")
+	configFlag := flag.String("config", defaultConfigPath(), "YAML file with default flag values")
+	completionFlag := flag.String("completion", "", "Print a completion script for bash, zsh or fish, to eval in the shell's rc file")
+	completeTags := flag.Bool(completeTagsFlag, false, "Internal: prints the tags in the history for the completion scripts")
+	defineShorthands(flag.CommandLine)
+	flag.CommandLine.Parse(expandShortFlags(flag.CommandLine, os.Args[1:]))
+
+	if *completionFlag != "" {
+		if err := printCompletion(os.Stdout, *completionFlag); err != nil {
+			fatal("invalid --completion", "err", err)
+		}
+		return
+	}
 
-	fmt.Printf("\nStarting %s timer for %s\n", task, duration.Round(time.Second))
+	config, err := loadConfig(*configFlag)
+	if err == nil {
+		err = applyConfig(*configFlag, config)
+	}
+	if err != nil {
+		fatal("cannot load config", "err", err)
+	}
+	if verbose {
+		*logLevelFlag = "debug"
+	}
+	if err := setupLogging(stderrWriter{}, *logLevelFlag, *logEncodingFlag); err != nil {
+		fatal(err.Error())
+	}
+	if *timezoneFlag != "" {
+		// Every clock time the timer reads, writes or shows is local.
+		loc, err := time.LoadLocation(*timezoneFlag)
+		if err != nil {
+			fatal("invalid --timezone", "err", err)
+		}
+		time.Local = loc
+	}
+	stopProfiling, err := startProfiling(*profileFlag, *memProfileFlag)
+	if err != nil {
+		fatal("cannot start profiling", "err", err)
+	}
+	defer stopProfiling()
 
-	for {
-		select {
-		case <-ticker.C:
-			remaining := time.Until(endTime).Round(time.Second)
-			if remaining <= 0 {
-				fmt.Printf("\r%s: \033[32mCompleted!\033[0m\n", task)
-				return
-			}
-			fmt.Printf("\r%s: %-10s remaining", task, remaining)
+	notificationsEnabled = !*noNotifyFlag
+	if *noColorFlag {
+		colorEnabled = false
+	}
+	if colors, err = lookupColorScheme(*colorSchemeFlag); err != nil {
+		fatal("invalid --color-scheme", "err", err)
+	}
+
+	if *stopFlag {
+		if err := stopDaemon(); err != nil {
+			fatal("cannot stop daemon", "err", err)
 		}
+		return
+	}
+	if *ctlFlag {
+		if err := runCtl(flag.Args()); err != nil {
+			fatal("cannot send command to daemon", "err", err)
+		}
+		return
+	}
+	if *daemonFlag {
+		if err := startDaemon(); err != nil {
+			fatal("cannot start daemon", "err", err)
+		}
+		return
 	}
-}
 
-func logHistory(task Task) error {
-	file, err := os.OpenFile(historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+	historyFile := defaultHistoryFile
+	if env := os.Getenv(historyFileEnv); env != "" {
+		historyFile = env
+	}
+	if *historyFileFlag != "" {
+		historyFile = *historyFileFlag
 	}
-	defer file.Close()
 
-	entry := fmt.Sprintf("%s|%s|%s\n",
-		task.Name,
-		task.Duration.String(),
-		time.Now().Format("2006-01-02 15:04:05"),
-	)
+	if *parallelFlag < 1 {
+		fatal("--parallel must be at least 1")
+	}
 
-	_, err = file.WriteString(entry)
-	return err
-}
+	if err := validateLogFormat(*logFormatFlag); err != nil {
+		fatal(err.Error())
+	}
+	if bellCount < 1 {
+		fatal("--repeat-bell must be at least 1")
+	}
+	if *maxLogBackupsFlag < 1 {
+		fatal("--max-log-backups must be at least 1")
+	}
+	if *tickIntervalFlag < minTickInterval {
+		fatal("--tick-interval is too short", "min", minTickInterval)
+	}
+	if err := validateCountdownStyle(*countdownStyleFlag); err != nil {
+		fatal(err.Error())
+	}
+	if *barWidthFlag < 1 {
+		fatal("--bar-width must be at least 1")
+	}
 
-func showHistory() error {
-	file, err := os.Open(historyFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println("No history available")
-			return nil
+	timer := NewTimer(Config{
+		HistoryFile:     historyFile,
+		LogFormat:       *logFormatFlag,
+		MaxLogSize:      int64(maxLogSize),
+		MaxLogBackups:   *maxLogBackupsFlag,
+		Parallel:        *parallelFlag,
+		ShortestFirst:   *sortQueueFlag,
+		CountUp:         *countUpFlag,
+		MaxTaskDuration: *maxTaskDurationFlag,
+		MaxQueueDepth:   *maxQueueDepthFlag,
+		TickInterval:    *tickIntervalFlag,
+		CountdownStyle:  *countdownStyleFlag,
+		BarWidth:        *barWidthFlag,
+		BeepAt:          beepAt,
+	})
+
+	if *completeTags {
+		if err := printHistoryTags(os.Stdout, timer); err != nil {
+			os.Exit(1)
 		}
-		return err
+		return
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	fmt.Println("\nTask History:")
-	fmt.Println("----------------------------------------")
-	for scanner.Scan() {
-		parts := strings.Split(scanner.Text(), "|")
-		if len(parts) != 3 {
-			continue
+	// "timer import --format <app> <file>" adds another app's export to
+	// the history.
+	if flag.Arg(0) == "import" {
+		if err := importHistory(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot import history", "err", err)
 		}
-		fmt.Printf("Task: %s\nDuration: %s\nCompleted: %s\n\n",
-			parts[0], parts[1], parts[2])
+		return
 	}
-	return scanner.Err()
-}
 
-func handleInput(cmdCh chan<- string) {
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		cmdCh <- scanner.Text()
+	// "timer export --format <app> [--out <file>]" writes the history for
+	// another app to import.
+	if flag.Arg(0) == "export" {
+		if err := exportHistory(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot export history", "err", err)
+		}
+		return
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Input error: %v\n", err)
+
+	// "timer calendar [--week|--month]" shows the time recorded per day.
+	if flag.Arg(0) == "calendar" {
+		if err := calendarCommand(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot show calendar", "err", err)
+		}
+		return
 	}
-	close(cmdCh)
-}
 
-func main() {
-	historyFlag := flag.Bool("history", false, "Show timer history")
-	flag.Parse()
+	// "timer goal set|status" sets and tracks daily and weekly goals.
+	if flag.Arg(0) == "goal" {
+		if err := goalCommand(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot run goal", "err", err)
+		}
+		return
+	}
 
-	if *historyFlag {
-		if err := showHistory(); err != nil {
-			fmt.Printf("Error showing history: %v\n", err)
+	// "timer project summary|history" reports the time spent per project.
+	if flag.Arg(0) == "project" {
+		if err := projectCommand(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot run project", "err", err)
 		}
 		return
 	}
 
-	cmdCh := make(chan string)
-	go handleInput(cmdCh)
+	// "timer rate [--period <n>d]" shows the completion rate and trend.
+	if flag.Arg(0) == "rate" {
+		if err := rateCommand(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot show rate", "err", err)
+		}
+		return
+	}
 
-	// fmt.Println("Timer App - Enter commands ('add', 'exit', or task duration)")
-	// fmt.Println("Format: add <task name> [flags]")
-	// fmt.Println("Example: add 'Study Session' -m 25 -s 30")
-	fmt.Print("$")
+	// "timer longest" and "timer shortest" list the extremes of the
+	// history.
+	if flag.Arg(0) == "longest" || flag.Arg(0) == "shortest" {
+		if err := extremesCommand(timer, flag.Args()[1:], flag.Arg(0) == "longest"); err != nil {
+			fatal("cannot list "+flag.Arg(0)+" tasks", "err", err)
+		}
+		return
+	}
 
-	for {
-		queueMux.Lock()
-		hasTasks := len(taskQueue) > 0
-		queueMux.Unlock()
-
-		if hasTasks {
-			queueMux.Lock()
-			task := taskQueue[0]
-			taskQueue = taskQueue[1:]
-			queueMux.Unlock()
-
-			done := make(chan struct{})
-			go func() {
-				startTimer(task.Name, task.Duration)
-				close(done)
-			}()
-
-			if err := logHistory(task); err != nil {
-				fmt.Printf("Error logging history: %v\n", err)
-			}
+	// "timer compare --from <date> --to <date> vs --from <date> --to
+	// <date>" sets two periods of history side by side.
+	if flag.Arg(0) == "compare" {
+		if err := compareCommand(timer, flag.Args()[1:]); err != nil {
+			fatal("cannot compare periods", "err", err)
+		}
+		return
+	}
 
-			// Wait for timer completion or new commands
-			for {
-				select {
-				case cmd, ok := <-cmdCh:
-					if !ok {
-						return
-					}
-					processCommand(cmd)
-				case <-done:
-					goto NextTask
-				}
-			}
-		NextTask:
-		} else {
-			select {
-			case cmd, ok := <-cmdCh:
-				if !ok {
-					return
-				}
-				processCommand(cmd)
-			default:
-				time.Sleep(100 * time.Millisecond)
-			}
+	// "timer validate <file>..." checks task files without running them.
+	if flag.Arg(0) == "validate" {
+		if flag.NArg() < 2 {
+			fatal("validate needs at least one task file")
 		}
+		if !validateTaskFiles(timer, flag.Args()[1:]) {
+			os.Exit(1)
+		}
+		return
 	}
-}
 
-func processCommand(cmd string) {
-	if strings.ToLower(cmd) == "exit" {
-		fmt.Println("Exiting...")
-		os.Exit(0)
+	from, err := parseDate(*fromFlag)
+	if err != nil {
+		fatal("invalid --from", "err", err)
+	}
+	to, err := parseDate(*toFlag)
+	if err != nil {
+		fatal("invalid --to", "err", err)
 	}
 
-	if !strings.HasPrefix(cmd, "add ") {
-		fmt.Println("Unknown command. Use 'add <task> [flags]' or 'exit'")
+	if *historyFlag {
+		entries, err := timer.History()
+		if err == nil {
+			entries = filterHistory(entries, from, to, tags)
+			err = sortHistory(entries, *sortFlag)
+		}
+		if err == nil && len(entries) == 0 && len(tags) > 0 && *formatFlag == "text" {
+			fmt.Printf("No tasks matching tag %s\n", tagList(tags))
+			return
+		}
+		if err == nil {
+			err = showHistory(entries, *formatFlag)
+		}
+		if err != nil {
+			fatal("cannot show history", "err", err)
+		}
 		return
 	}
 
-	args := strings.Fields(cmd)[1:]
-	var flagsIndex int
-	for i, arg := range args {
-		if strings.HasPrefix(arg, "-") {
-			flagsIndex = i
-			break
+	if *heatmapFlag != 0 {
+		entries, err := timer.History()
+		if err != nil {
+			fatal("cannot read history", "err", err)
+		}
+		out := os.Stdout
+		if *heatmapOutFlag != "" {
+			if out, err = os.Create(*heatmapOutFlag); err != nil {
+				fatal("cannot create heatmap file", "err", err)
+			}
+			defer out.Close()
+		}
+		if err := writeHeatmap(out, entries, *heatmapFlag); err != nil {
+			fatal("cannot write heatmap", "err", err)
 		}
+		return
 	}
 
-	if flagsIndex == 0 {
-		fmt.Println("Invalid command format. Use: add <task name> [flags]")
+	if *statsFlag {
+		entries, err := timer.History()
+		if err == nil {
+			err = showStats(filterHistory(entries, from, to, tags))
+		}
+		if err != nil {
+			fatal("cannot show stats", "err", err)
+		}
 		return
 	}
 
-	taskName := strings.Join(args[:flagsIndex], " ")
-	durationStr := strings.Join(args[flagsIndex:], " ")
+	if !dryRun {
+		timer.Config.QueueFile = defaultQueueFile
+		timer.Config.StateFile = defaultStateFile
+	}
+	if *outputFlag != "" {
+		output, err := os.OpenFile(*outputFlag, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fatal("cannot open output file", "err", err)
+		}
+		defer output.Close()
+		timer.Config.Output = logWriter{w: output}
+	}
+	if *quietFlag && timer.Config.Output == nil {
+		// Progress is dropped; announcements such as completions still
+		// reach stdout.
+		timer.Config.Output = io.Discard
+	}
 
-	duration, err := parseDuration(durationStr)
-	if err != nil {
-		fmt.Printf("Error parsing duration: %v\n", err)
-		return
+	if *pomodoroFlag {
+		pomodoro := &PomodoroSchedule{
+			Work:       *workFlag,
+			ShortBreak: *shortBreakFlag,
+			LongBreak:  *longBreakFlag,
+			Cycles:     *cyclesFlag,
+		}
+		if err := pomodoro.validate(); err != nil {
+			fatal(err.Error())
+		}
+		timer.Config.Pomodoro = pomodoro
+	}
+
+	// Traces go to the OpenTelemetry collector named in the environment.
+	if tracer := newTracerFromEnv(); tracer != nil && !dryRun {
+		defer tracer.close()
+		timer.Tracer = tracer
+	}
+	if *syncHistoryFlag != "" && !dryRun {
+		syncer := newHistorySync(*syncHistoryFlag)
+		defer syncer.close()
+		timer.OnHistory = syncer.send
+	}
+	if goals, err := loadGoals(goalsPath()); err != nil {
+		slog.Warn("cannot load goals", "file", goalsPath(), "err", err)
+	} else if len(goals) > 0 {
+		onHistory := timer.OnHistory
+		timer.OnHistory = func(entry HistoryEntry) {
+			if onHistory != nil {
+				onHistory(entry)
+			}
+			checkGoals(timer, goals, entry)
+		}
+	}
+	timer.OnMilestone = func(task Task, percent int) {
+		fmt.Print("\a")
+		notifyMilestone(task, percent)
+	}
+	timer.OnComplete = func(task Task) {
+		notifyCompleted(task)
+		playAlert(alertSoundFile)
+		ringBell()
+		runAfterEach(task)
+	}
+	timer.OnSessionEnd = func(session Session) {
+		if *sessionReportFlag {
+			printSessionReport(session)
+		}
+		runAfterAll()
+	}
+
+	if err := timer.loadQueue(); err != nil {
+		slog.Warn("cannot load queue", "file", timer.Config.QueueFile, "err", err)
+	}
+	var interrupted []Task
+	if timer.Config.StateFile != "" {
+		interrupted, err = loadState(timer.Config.StateFile)
+		if err != nil {
+			slog.Warn("cannot load interrupted tasks", "err", err)
+		}
+	}
+	if *taskFileFlag != "" {
+		if err := loadTaskFiles(timer, *taskFileFlag); err != nil {
+			fatal("cannot load task file", "err", err)
+		}
+	}
+	if *taskYAMLFlag != "" {
+		n, err := loadYAMLTasks(timer, strings.NewReader(*taskYAMLFlag), "--task-yaml")
+		if err != nil {
+			fatal("cannot load --task-yaml", "err", err)
+		}
+		fmt.Printf("Loaded %d task(s) from --task-yaml\n", n)
+	}
+
+	for _, value := range cronFlags {
+		schedule, err := parseCron(value, flag.Args())
+		if err == nil {
+			err = timer.CheckTask(schedule.task)
+		}
+		if err != nil {
+			fatal("invalid --cron", "err", err)
+		}
+		cronSchedules = append(cronSchedules, schedule)
+		if !dryRun {
+			go schedule.run(timer)
+		}
 	}
 
-	if duration <= 0 {
-		fmt.Println("Duration must be positive")
+	if err := loadAliases(aliasesPath()); err != nil {
+		slog.Warn("cannot load aliases", "file", aliasesPath(), "err", err)
+	}
+
+	if *serveFlag != "" {
+		go func() {
+			slog.Debug("HTTP API goroutine started", "addr", *serveFlag)
+			if err := http.ListenAndServe(*serveFlag, newServer(timer)); err != nil {
+				slog.Warn("cannot serve HTTP API", "addr", *serveFlag, "err", err)
+			}
+		}()
+	}
+
+	if *metricsPortFlag > 0 {
+		addr := fmt.Sprintf(":%d", *metricsPortFlag)
+		handler := newMetricsHandler(timer)
+		go func() {
+			slog.Debug("metrics goroutine started", "addr", addr)
+			if err := http.ListenAndServe(addr, handler); err != nil {
+				slog.Warn("cannot serve metrics", "addr", addr, "err", err)
+			}
+		}()
+	}
+
+	if *daemonChild {
+		// There is nobody to ask, so the daemon resumes what it was
+		// running when it was last stopped.
+		for i, task := range interrupted {
+			timer.Insert(i, task)
+			slog.Info("resuming interrupted task", "task", task.Name, "remaining", task.Remaining.Round(time.Second))
+		}
+		go func() {
+			if err := timer.Start(); err != nil {
+				slog.Error("cannot start timer", "err", err)
+			}
+		}()
+		runDaemon(timer)
 		return
 	}
 
-	queueMux.Lock()
-	taskQueue = append(taskQueue, Task{Name: taskName, Duration: duration})
-	queueMux.Unlock()
+	cmdCh := make(chan string)
+	var watchCh chan string
+	if *watchFileFlag != "" {
+		watchCh = make(chan string)
+		go watchFile(*watchFileFlag, watchCh)
+	}
 
-	fmt.Printf("Added task: %s (%s)\n", taskName, duration.Round(time.Second))
-}
\ No newline at end of file
+	// Piped input is a batch: the timer exits once it has run the tasks
+	// rather than when the input ends.
+	batch := !isTerminal(os.Stdin)
+	// interrupt ends the session as SIGINT and SIGTERM do, for Ctrl-C
+	// read as a key by the full-screen interface.
+	interruptCtx, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	var ui *tui
+	if !batch && !*noTUIFlag && isTerminal(os.Stdout) {
+		ui, err = startTUI(timer, cmdCh, interrupt)
+		if err != nil {
+			slog.Debug("full-screen interface unavailable", "err", err)
+		}
+	}
+	if ui == nil {
+		go handleInput(cmdCh)
+	} else {
+		defer ui.stop()
+	}
+
+	// fmt.Println("Timer App - Enter commands ('add', 'exit', or task duration)")
+	// fmt.Println("Format: add <task name> [flags]")
+	// fmt.Println("Example: add 'Study Session' -m 25 -s 30")
+	if !batch {
+		if ui == nil {
+			fmt.Print("$")
+		}
+		offerResume(timer, interrupted)
+	}
+
+	if !dryRun {
+		go func() {
+			if err := timer.Start(); err != nil {
+				slog.Error("cannot start timer", "err", err)
+			}
+		}()
+	}
+
+	// timeoutCh fires when the session may have been idle for --timeout;
+	// if it turns out it was not, the check is rearmed for the rest.
+	timeoutCh := make(chan struct{}, 1)
+	checkTimeout := func() { timeoutCh <- struct{}{} }
+	if *timeoutFlag > 0 {
+		time.AfterFunc(*timeoutFlag, checkTimeout)
+	}
+	idleCh := make(chan struct{})
+	// SIGINT and SIGTERM end the session cleanly, keeping the running
+	// tasks for the next start.
+	signalCtx, stopSignals := signal.NotifyContext(interruptCtx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+loop:
+	for {
+		select {
+		case cmd, ok := <-cmdCh:
+			if !ok && watchCh != nil {
+				// Keep watching the file once stdin is closed.
+				cmdCh = nil
+				continue
+			}
+			if !ok && batch && !dryRun {
+				cmdCh = nil
+				go func() {
+					waitUntilIdle(timer)
+					close(idleCh)
+				}()
+				continue
+			}
+			if !ok {
+				break loop
+			}
+			if batch {
+				cmd = batchCommand(cmd)
+			}
+			if !processCommand(timer, cmd) {
+				timer.Stop()
+				break loop
+			}
+		case cmd := <-watchCh:
+			processCommand(timer, cmd)
+		case <-idleCh:
+			timer.Stop()
+			break loop
+		case <-signalCtx.Done():
+			fmt.Println("\nInterrupted, saving running tasks")
+			timer.Interrupt()
+			break loop
+		case <-timeoutCh:
+			if idle := timer.IdleFor(); idle < *timeoutFlag {
+				time.AfterFunc(*timeoutFlag-idle, checkTimeout)
+				continue
+			}
+			fmt.Println("\nSession timed out")
+			timer.Stop()
+			break loop
+		}
+	}
+	if dryRun {
+		printDryRunSummary(timer)
+		runAfterAll()
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer collects output written from another goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) copyFrom(r io.Reader) {
+	chunk := make([]byte, 1024)
+	for {
+		n, err := r.Read(chunk)
+		b.mu.Lock()
+		b.buf.Write(chunk[:n])
+		b.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestMainIntegration(t *testing.T) {
+	dir := t.TempDir()
+	historyPath := filepath.Join(dir, "history.log")
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	t.Setenv(historyFileEnv, historyPath)
+
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldArgs, oldStdin, oldStdout := os.Args, os.Stdin, os.Stdout
+	os.Args = []string{"timer", "--no-notify"}
+	os.Stdin, os.Stdout = stdinR, stdoutW
+	defer func() {
+		os.Args, os.Stdin, os.Stdout = oldArgs, oldStdin, oldStdout
+	}()
+
+	var output syncBuffer
+	copied := make(chan struct{})
+	go func() {
+		output.copyFrom(stdoutR)
+		close(copied)
+	}()
+
+	exited := make(chan struct{})
+	go func() {
+		main()
+		close(exited)
+	}()
+
+	io.WriteString(stdinW, "add Task1 -s 2\n")
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		data, _ := os.ReadFile(historyPath)
+		if strings.Contains(string(data), "Task1|2s|") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("history was not written; output so far:\n%s", output.String())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	io.WriteString(stdinW, "exit\n")
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("main did not return after exit")
+	}
+	stdoutW.Close()
+	<-copied
+	stdinW.Close()
+
+	out := output.String()
+	for _, want := range []string{"Added task: Task1 (2s)", "Starting Task1 timer for 2s", "Task1: \033[32mCompleted!", "Exiting..."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the
+// timer_task_duration_seconds histogram: 1, 5, 15, 25, 30 and 45
+// minutes, and 1 and 2 hours.
+var durationBuckets = []float64{60, 300, 900, 1500, 1800, 2700, 3600, 7200}
+
+// metrics counts what the timer does, from its events, for Prometheus to
+// scrape in the text exposition format.
+type metrics struct {
+	timer *Timer
+	start time.Time
+
+	mu    sync.Mutex
+	tasks map[string]int
+	// buckets[i] counts the runs no longer than durationBuckets[i].
+	buckets []int
+	sum     time.Duration
+	count   int
+}
+
+// newMetricsHandler serves the metrics of t at /metrics.
+func newMetricsHandler(t *Timer) http.Handler {
+	m := &metrics{
+		timer:   t,
+		start:   time.Now(),
+		tasks:   make(map[string]int),
+		buckets: make([]int, len(durationBuckets)),
+	}
+	events := make(chan Event, 64)
+	t.Subscribe(events)
+	go m.record(events)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", m.serve)
+	return mux
+}
+
+// record counts every task that stops running, with how long it ran.
+func (m *metrics) record(events <-chan Event) {
+	slog.Debug("metrics recorder goroutine started")
+	for event := range events {
+		var status string
+		switch event.Type {
+		case EventTaskCompleted:
+			status = StatusCompleted
+		case EventTaskSkipped:
+			status = StatusSkipped
+		case EventTaskCancelled:
+			status = statusCancelled
+		default:
+			continue
+		}
+		ran := event.Task.Duration - max(event.Remaining, 0)
+
+		m.mu.Lock()
+		m.tasks[status]++
+		for i, bound := range durationBuckets {
+			if ran.Seconds() <= bound {
+				m.buckets[i]++
+			}
+		}
+		m.sum += ran
+		m.count++
+		m.mu.Unlock()
+	}
+}
+
+func (m *metrics) serve(w http.ResponseWriter, r *http.Request) {
+	// The queue is read as it is now, so the gauge is never stale.
+	depth := len(m.timer.Queue())
+
+	var b strings.Builder
+	m.mu.Lock()
+	b.WriteString("# HELP timer_tasks_total Tasks that stopped running, by how they ended.\n")
+	b.WriteString("# TYPE timer_tasks_total counter\n")
+	for _, status := range []string{StatusCompleted, StatusSkipped, statusCancelled} {
+		fmt.Fprintf(&b, "timer_tasks_total{status=%q} %d\n", status, m.tasks[status])
+	}
+
+	b.WriteString("# HELP timer_task_duration_seconds How long tasks ran before they stopped.\n")
+	b.WriteString("# TYPE timer_task_duration_seconds histogram\n")
+	for i, bound := range durationBuckets {
+		fmt.Fprintf(&b, "timer_task_duration_seconds_bucket{le=\"%g\"} %d\n", bound, m.buckets[i])
+	}
+	fmt.Fprintf(&b, "timer_task_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(&b, "timer_task_duration_seconds_sum %g\n", m.sum.Seconds())
+	fmt.Fprintf(&b, "timer_task_duration_seconds_count %d\n", m.count)
+	m.mu.Unlock()
+
+	b.WriteString("# HELP timer_queue_depth Tasks waiting in the queue.\n")
+	b.WriteString("# TYPE timer_queue_depth gauge\n")
+	fmt.Fprintf(&b, "timer_queue_depth %d\n", depth)
+
+	b.WriteString("# HELP timer_session_uptime_seconds Time since the timer started.\n")
+	b.WriteString("# TYPE timer_session_uptime_seconds gauge\n")
+	fmt.Fprintf(&b, "timer_session_uptime_seconds %g\n", time.Since(m.start).Seconds())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, b.String())
+}
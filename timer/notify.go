@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// notificationsEnabled is cleared by --no-notify.
+var notificationsEnabled = true
+
+// notifyCompleted fires a desktop notification for a finished task
+// without holding up the timer loop.
+func notifyCompleted(task Task) {
+	if !notificationsEnabled {
+		return
+	}
+
+	sendNotification(task, fmt.Sprintf("%s completed (%s)", task.Name, task.Duration))
+}
+
+// notifyMilestone fires a desktop notification for a --beep-at milestone.
+func notifyMilestone(task Task, percent int) {
+	if !notificationsEnabled {
+		return
+	}
+	sendNotification(task, fmt.Sprintf("%s is %d%% done", task.Name, percent))
+}
+
+// notifyGoalReached fires a desktop notification once the time recorded
+// in a period reaches its goal.
+func notifyGoalReached(period string, goal time.Duration) {
+	if !notificationsEnabled {
+		return
+	}
+	go func() {
+		if err := notify("Timer", fmt.Sprintf("%s goal of %s reached", strings.ToUpper(period[:1])+period[1:], shortDuration(goal))); err != nil {
+			slog.Warn("cannot send notification", "goal", period, "err", err)
+		}
+	}()
+}
+
+func sendNotification(task Task, message string) {
+	go func() {
+		if err := notify("Timer", message); err != nil {
+			slog.Warn("cannot send notification", "task", task.Name, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,9 @@
+//go:build linux
+
+package main
+
+import "os/exec"
+
+func notify(title, message string) error {
+	return exec.Command("notify-send", title, message).Run()
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+func notify(title, message string) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func notify(title, message string) error {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	script := fmt.Sprintf("New-BurntToastNotification -Text %s, %s", quote(title), quote(message))
+	return exec.Command("powershell", "-NoProfile", "-Command", script).Run()
+}
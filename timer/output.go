@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ansiEscapeRe = regexp.MustCompile(`\x1b(?:\[[0-9;]*[A-Za-z]|[78])`)
+
+// logWriter turns terminal output into a log for --output: every line,
+// including each carriage-return redraw of the countdown, is written on
+// its own line behind a timestamp, without ANSI escape codes.
+type logWriter struct {
+	w io.Writer
+}
+
+func (l logWriter) Write(p []byte) (int, error) {
+	stamp := time.Now().Format(historyTimeLayout)
+	text := ansiEscapeRe.ReplaceAllString(string(p), "")
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(l.w, "%s %s\n", stamp, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// pomodoroTag is carried by the tasks the schedule adds, so they can
+	// be filtered in the queue and in history.
+	pomodoroTag = "pomodoro"
+	// pomodoroPrefix marks them out while they count down.
+	pomodoroPrefix = "[pomodoro]"
+)
+
+// PomodoroSchedule produces an endless sequence of work sessions and
+// breaks: Cycles work sessions separated by short breaks, then a long
+// break, then the sequence starts over.
+type PomodoroSchedule struct {
+	Work       time.Duration
+	ShortBreak time.Duration
+	LongBreak  time.Duration
+	Cycles     int
+
+	step int
+}
+
+func (p *PomodoroSchedule) validate() error {
+	if p.Work <= 0 || p.ShortBreak <= 0 || p.LongBreak <= 0 {
+		return fmt.Errorf("pomodoro durations must be positive")
+	}
+	if p.Cycles < 1 {
+		return fmt.Errorf("pomodoro cycles must be at least 1")
+	}
+	return nil
+}
+
+// next returns the task for the following phase of the schedule.
+func (p *PomodoroSchedule) next() Task {
+	task := p.peek()
+	p.step++
+	return task
+}
+
+// peek returns the task next would, without moving on.
+func (p *PomodoroSchedule) peek() Task {
+	cycle := p.step/2%p.Cycles + 1
+	isBreak := p.step%2 == 1
+
+	var name string
+	var duration time.Duration
+	switch {
+	case !isBreak:
+		name = fmt.Sprintf("Work %d/%d", cycle, p.Cycles)
+		duration = p.Work
+	case cycle == p.Cycles:
+		name = "Long break"
+		duration = p.LongBreak
+	default:
+		name = "Short break"
+		duration = p.ShortBreak
+	}
+	return Task{Name: name, Duration: duration, Remaining: duration, Tags: []string{pomodoroTag}}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startProfiling begins a CPU profile written to cpuPath when it is set.
+// The returned function ends it, dumps the goroutines to
+// cpuPath.goroutines and writes a heap profile to memPath when that is
+// set; it is meant to run at exit.
+func startProfiling(cpuPath, memPath string) (func(), error) {
+	var cpuFile *os.File
+	if cpuPath != "" {
+		f, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		cpuFile = f
+	}
+
+	return func() {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			cpuFile.Close()
+			if err := writeProfile("goroutine", cpuPath+".goroutines", 2); err != nil {
+				slog.Warn("cannot write goroutine dump", "err", err)
+			}
+		}
+		if memPath != "" {
+			// Collect first so the profile reflects live memory.
+			runtime.GC()
+			if err := writeProfile("heap", memPath, 0); err != nil {
+				slog.Warn("cannot write heap profile", "err", err)
+			}
+		}
+	}, nil
+}
+
+// writeProfile writes the named runtime/pprof profile to path.
+func writeProfile(name, path string, debug int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(name).WriteTo(f, debug); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
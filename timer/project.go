@@ -0,0 +1,107 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// noProject stands for the entries without a project in reports.
+const noProject = "(none)"
+
+// projectCommand handles "timer project summary", totalling the history
+// by project, and "timer project --name <project> history [--format
+// <format>]", showing the history of one project.
+func projectCommand(t *Timer, args []string) error {
+	const usage = "usage: timer project summary | timer project --name <project> history [--format text|json|csv|markdown]"
+	name, args, hasName, err := takeOption(args, "name")
+	var format string
+	if err == nil {
+		format, args, _, err = takeOption(args, "format")
+	}
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New(usage)
+	}
+
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "summary":
+		if hasName {
+			return errors.New(usage)
+		}
+		return showProjectSummary(entries)
+	case "history":
+		if !hasName {
+			return errors.New(usage)
+		}
+		if format == "" {
+			format = "text"
+		}
+		var matched []HistoryEntry
+		for _, e := range entries {
+			if e.Project == name {
+				matched = append(matched, e)
+			}
+		}
+		return showHistory(matched, format)
+	}
+	return errors.New(usage)
+}
+
+// showProjectSummary prints the count, total and average time of the
+// entries in each project, busiest first.
+func showProjectSummary(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		fmt.Println("No history available")
+		return nil
+	}
+
+	type summary struct {
+		name  string
+		count int
+		total time.Duration
+	}
+	byProject := make(map[string]*summary)
+	for _, e := range entries {
+		name := e.Project
+		if name == "" {
+			name = noProject
+		}
+		s, ok := byProject[name]
+		if !ok {
+			s = &summary{name: name}
+			byProject[name] = s
+		}
+		s.count++
+		s.total += e.Duration
+	}
+
+	projects := make([]*summary, 0, len(byProject))
+	for _, s := range byProject {
+		projects = append(projects, s)
+	}
+	slices.SortFunc(projects, func(a, b *summary) int {
+		if c := cmp.Compare(b.total, a.total); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Project\tCount\tTotal\tAverage")
+	for _, s := range projects {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n",
+			s.name, s.count, s.total, (s.total / time.Duration(s.count)).Round(time.Second))
+	}
+	return w.Flush()
+}
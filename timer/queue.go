@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+)
+
+const defaultQueueFile = "timer_queue.json"
+
+// persist saves the queue, reporting rather than returning failures so
+// callers can keep going. The caller must hold t.mu.
+func (t *Timer) persist() {
+	if err := t.saveQueue(); err != nil {
+		slog.Warn("cannot save queue", "file", t.Config.QueueFile, "err", err)
+	}
+}
+
+// saveQueue writes the pending tasks to Config.QueueFile so they survive a
+// restart. The caller must hold t.mu.
+func (t *Timer) saveQueue() error {
+	if t.Config.QueueFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(t.queue, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.Config.QueueFile, data, 0644)
+}
+
+// loadQueue restores the tasks saved by saveQueue. A missing file leaves
+// the queue empty.
+func (t *Timer) loadQueue() error {
+	if t.Config.QueueFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(t.Config.QueueFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return err
+	}
+	for i := range tasks {
+		if tasks[i].Remaining <= 0 {
+			tasks[i].Remaining = tasks[i].Duration
+		}
+	}
+
+	t.mu.Lock()
+	t.queue = append(tasks, t.queue...)
+	if t.Config.ShortestFirst {
+		slices.SortStableFunc(t.queue, func(a, b Task) int {
+			switch {
+			case t.runsBefore(a, b):
+				return -1
+			case t.runsBefore(b, a):
+				return 1
+			}
+			return 0
+		})
+	}
+	t.mu.Unlock()
+
+	t.notify()
+	return nil
+}
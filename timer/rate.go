@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sparkBlocks draw a sparkline from lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// rateCommand handles "timer rate [--period <n>d]", showing how many
+// tasks were completed on each of the last n days, 30 by default.
+func rateCommand(t *Timer, args []string) error {
+	period, args, _, err := takeOption(args, "period")
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return errors.New("usage: timer rate [--period 7d|30d|90d]")
+	}
+	days := 30
+	if period != "" {
+		days, err = strconv.Atoi(strings.TrimSuffix(period, "d"))
+		if err != nil || days < 1 || !strings.HasSuffix(period, "d") {
+			return fmt.Errorf("invalid --period %q: want a number of days such as 7d, 30d or 90d", period)
+		}
+	}
+
+	entries, err := t.History()
+	if err != nil {
+		return err
+	}
+	showRate(entries, time.Now(), days)
+	return nil
+}
+
+// dailyCompletions counts the tasks completed on each of the days days
+// ending with today's, oldest first.
+func dailyCompletions(entries []HistoryEntry, now time.Time, days int) []int {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	first := today.AddDate(0, 0, 1-days)
+	counts := make([]int, days)
+	for _, e := range entries {
+		if e.Status != StatusCompleted {
+			continue
+		}
+		at := e.CompletedAt.Local()
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.Local)
+		// Counting in calendar days keeps daylight saving changes out.
+		if i := daysBetween(first, day); i >= 0 && i < days {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// daysBetween returns the number of calendar days from a to b.
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+func showRate(entries []HistoryEntry, now time.Time, days int) {
+	// Two extra weeks give the rolling average and the trend something
+	// to compare with even for short periods.
+	counts := dailyCompletions(entries, now, days+14)
+	window := counts[14:]
+
+	total := 0
+	for _, n := range window {
+		total += n
+	}
+	fmt.Printf("Last %d days: %d task(s) completed, %.1f per day\n", days, total, float64(total)/float64(days))
+	fmt.Println(sparkline(window))
+
+	last, previous := average(counts[len(counts)-7:]), average(counts[len(counts)-14:len(counts)-7])
+	fmt.Printf("Rolling 7-day average: %.1f per day (previous 7 days: %.1f)\n", last, previous)
+	fmt.Printf("Trend: %s\n", trend(last, previous))
+}
+
+func average(counts []int) float64 {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return float64(total) / float64(len(counts))
+}
+
+// trend compares two averages, calling changes under a tenth flat.
+func trend(last, previous float64) string {
+	switch {
+	case last > previous*1.1 && last-previous >= 0.1:
+		return "up"
+	case last < previous*0.9 && previous-last >= 0.1:
+		return "down"
+	}
+	return "flat"
+}
+
+// sparkline draws counts as blocks scaled to the largest.
+func sparkline(counts []int) string {
+	highest := 0
+	for _, n := range counts {
+		highest = max(highest, n)
+	}
+	var b strings.Builder
+	for _, n := range counts {
+		i := 0
+		if highest > 0 {
+			i = n * (len(sparkBlocks) - 1) / highest
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	return b.String()
+}
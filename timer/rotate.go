@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Defaults for --max-log-size and --max-log-backups.
+const (
+	defaultMaxLogSize    = 10 << 20
+	defaultMaxLogBackups = 3
+)
+
+// rotateIfNeeded renames the history file at path to path.1, shifting
+// older backups up to path.<backups>, once it has grown to maxSize bytes.
+// The oldest backup is overwritten, and backups below 1 keep one. A
+// maxSize of 0 disables rotation.
+func rotateIfNeeded(path string, maxSize int64, backups int) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Size() < maxSize {
+		return nil
+	}
+
+	// The history is never simply deleted; at least one backup is kept.
+	backups = max(backups, 1)
+	for i := backups - 1; i >= 1; i-- {
+		err := os.Rename(backupName(path, i), backupName(path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(path, backupName(path, 1))
+}
+
+func backupName(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// byteSize is a flag value holding a size in bytes, written as a plain
+// number or with a KB, MB or GB suffix.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	n := int64(*b)
+	for _, unit := range []struct {
+		name string
+		size int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n >= unit.size && n%unit.size == 0 {
+			return strconv.FormatInt(n/unit.size, 10) + unit.name
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func (b *byteSize) Set(s string) error {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, suffix := range []struct {
+		name string
+		size int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, suffix.name) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, suffix.name))
+			unit = suffix.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q (want e.g. 10MB, 512KB or a number of bytes)", s)
+	}
+	*b = byteSize(n * unit)
+	return nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command is one of the commands typed at the prompt, piped in or read
+// from a watched file.
+type command struct {
+	// Use is the command's name followed by its arguments, as shown in
+	// help and usage errors.
+	Use string
+	// Short is a one-line description for the list shown by help.
+	Short string
+	// Long, if set, adds detail to "help <command>".
+	Long string
+	// Args checks the number of arguments before Run is called; nil
+	// accepts any.
+	Args func(args []string) error
+	// Run carries out the command, returning errUsage for arguments it
+	// cannot make sense of and any other error to be reported.
+	Run func(t *Timer, args []string) error
+}
+
+// Name is the first word of Use.
+func (c *command) Name() string {
+	name, _, _ := strings.Cut(c.Use, " ")
+	return name
+}
+
+var (
+	// errUsage is returned for badly formed arguments, and is reported
+	// by showing how the command is used.
+	errUsage = errors.New("invalid command format")
+	// errExit is returned by the exit command to end the session.
+	errExit = errors.New("exit")
+)
+
+func noArgs(args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	return nil
+}
+
+func exactArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) != n {
+			return errUsage
+		}
+		return nil
+	}
+}
+
+func minArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) < n {
+			return errUsage
+		}
+		return nil
+	}
+}
+
+// commands lists every command in the order help shows them. It is
+// filled in by init because help refers back to it.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			Use:   "add <task name> [at HH:MM] <flags|duration> [--priority high|normal|low] [--repeat <n>|--repeat-forever] [--tag <label>]... [--project <name>]",
+			Short: "Queue a task",
+			Long: "The duration is written like 25m, 1h30m or PT1H30M, or with the flags -h, -m and -s.\n" +
+				"Quote a task name to keep words that look like durations in it: add 'Chapter 3' 45m.",
+			Run: addTask,
+		},
+		{
+			Use:   "list [--tag <label>]... [--schedules]",
+			Short: "Show the running and pending tasks",
+			Long:  "With --schedules, shows the --cron schedules and when each next adds its task instead.",
+			Run: func(t *Timer, args []string) error {
+				if schedules, _ := takeBoolOption(args, "schedules"); schedules {
+					listSchedules()
+					return nil
+				}
+				tags, args, err := takeRepeatedOption(args, "tag")
+				if err != nil {
+					return err
+				}
+				if len(args) != 0 {
+					return errUsage
+				}
+				listTasks(t, tags...)
+				return nil
+			},
+		},
+		{
+			Use:   "filter <tag> [tag]...",
+			Short: "Show the tasks carrying any of the tags",
+			Args:  minArgs(1),
+			Run: func(t *Timer, args []string) error {
+				listTasks(t, args...)
+				return nil
+			},
+		},
+		{Use: "remove <n>", Short: "Remove a pending task", Args: exactArgs(1), Run: removeTask},
+		{Use: "rename <n|current> <new name>", Short: "Rename a pending or the running task", Args: minArgs(2), Run: renameTask},
+		{Use: "swap <i> <j>", Short: "Swap two pending tasks", Args: exactArgs(2), Run: swapTasks},
+		{Use: "move <i> <j>", Short: "Move a pending task to another position", Args: exactArgs(2), Run: moveTask},
+		{Use: "duplicate <n|current>", Short: "Queue a copy of a task", Args: exactArgs(1), Run: duplicateTask},
+		{Use: "undo", Short: "Reverse the last add or remove", Args: noArgs, Run: undo},
+		{
+			Use:   "clear",
+			Short: "Remove every pending task",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				fmt.Printf("Cleared %d pending task(s)\n", t.Clear())
+				return nil
+			},
+		},
+		{
+			Use:   "note <text>",
+			Short: "Attach a note to the running task's history entry",
+			Args:  minArgs(1),
+			Run: func(t *Timer, args []string) error {
+				if err := t.AddNote(strings.Join(args, " ")); err != nil {
+					return fmt.Errorf("cannot add note: %w", err)
+				}
+				fmt.Println("Note added")
+				return nil
+			},
+		},
+		{
+			Use:   "pause",
+			Short: "Pause the running timer",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				if err := t.Pause(); err != nil {
+					return fmt.Errorf("cannot pause: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Use:   "resume",
+			Short: "Resume the paused timer",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				if err := t.Resume(); err != nil {
+					return fmt.Errorf("cannot resume: %w", err)
+				}
+				return nil
+			},
+		},
+		{Use: "extend <duration>", Short: "Add time to the running timer", Args: minArgs(1), Run: extendTimer},
+		{Use: "shorten <duration>", Short: "Take time off the running timer", Args: minArgs(1), Run: shortenTimer},
+		{
+			Use:   "done",
+			Short: "Finish the running task now, as completed",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				if err := t.Done(); err != nil {
+					return fmt.Errorf("cannot finish: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Use:   "skip",
+			Short: "Stop the running task and go on to the next",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				if err := t.Skip(); err != nil {
+					return fmt.Errorf("cannot skip: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Use:   "cancel",
+			Short: "Stop the running task without going on",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				if err := t.Cancel(); err != nil {
+					return fmt.Errorf("cannot cancel: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Use:   "forecast",
+			Short: "Estimate when the running and pending tasks will finish",
+			Long:  "Repeats, start times and --parallel are allowed for, and in --pomodoro mode so is the break the queue runs into.",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				return showForecast(t)
+			},
+		},
+		{Use: "search <term> [--regex]", Short: "Find tasks in the history by name", Run: searchTasks},
+		{
+			Use:   "streak",
+			Short: "Show the run of days with a completed task",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				entries, err := t.History()
+				if err != nil {
+					return fmt.Errorf("cannot read history: %w", err)
+				}
+				showStreak(entries, time.Now())
+				return nil
+			},
+		},
+		{
+			Use:   "reset-history [--yes]",
+			Short: "Archive the history file and start a new one",
+			Long:  "Asks before archiving unless --yes is given, which piped input needs.",
+			Run:   resetHistory,
+		},
+		{
+			Use:   "backup-history --s3-bucket <bucket> [--s3-key <key>] [--compress]",
+			Short: "Upload the history file to S3",
+			Long:  "The key defaults to the history file's name, with .gz added when compressing.",
+			Run:   backupHistory,
+		},
+		{
+			Use:   "alias <shortcut> <command>",
+			Short: "Define a shortcut for a command",
+			Long:  "Aliases are saved to " + aliasesPath() + ". Words typed after a shortcut are added to its command.",
+			Args:  minArgs(2),
+			Run: func(t *Timer, args []string) error {
+				return defineAlias(args)
+			},
+		},
+		{
+			Use:   "aliases",
+			Short: "List the aliases",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				listAliases()
+				return nil
+			},
+		},
+		{
+			Use:   "unalias <shortcut>",
+			Short: "Remove an alias",
+			Args:  exactArgs(1),
+			Run: func(t *Timer, args []string) error {
+				return removeAlias(args)
+			},
+		},
+		{
+			Use:   "help [command]",
+			Short: "List the commands, or show how to use one",
+			Run: func(t *Timer, args []string) error {
+				if len(args) == 0 {
+					listCommands()
+					return nil
+				}
+				c := lookupCommand(args[0])
+				if c == nil {
+					return fmt.Errorf("unknown command %q", args[0])
+				}
+				showHelp(c)
+				return nil
+			},
+		},
+		{
+			Use:   "exit",
+			Short: "End the session",
+			Args:  noArgs,
+			Run: func(t *Timer, args []string) error {
+				return errExit
+			},
+		},
+	}
+}
+
+// lookupCommand returns the command called name, or nil if there is
+// none.
+func lookupCommand(name string) *command {
+	name = strings.ToLower(name)
+	for _, c := range commands {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// runCommand checks the arguments of c and runs it, reporting whether
+// the session should keep going.
+func runCommand(t *Timer, c *command, args []string) bool {
+	if slices.Contains(args, "--help") {
+		showHelp(c)
+		return true
+	}
+
+	var err error
+	if c.Args != nil {
+		err = c.Args(args)
+	}
+	if err == nil {
+		err = c.Run(t, args)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, errExit):
+		fmt.Println("Exiting...")
+		return false
+	case errors.Is(err, errUsage):
+		fmt.Printf("Invalid command format. Use: %s\n", c.Use)
+	case errors.Is(err, errAddUsage):
+		fmt.Println(err)
+	default:
+		printDurationError("Error", err)
+	}
+	return true
+}
+
+func listCommands() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "%s\t%s\n", c.Name(), c.Short)
+	}
+	w.Flush()
+	fmt.Println("Type 'help <command>' or '<command> --help' for more.")
+}
+
+func showHelp(c *command) {
+	fmt.Printf("Usage: %s\n\n%s\n", c.Use, c.Short)
+	if c.Long != "" {
+		fmt.Printf("\n%s\n", c.Long)
+	}
+}
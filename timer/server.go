@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// taskJSON is the wire form of a Task in the HTTP API.
+type taskJSON struct {
+	ID        int      `json:"id,omitempty"`
+	Name      string   `json:"name"`
+	Duration  string   `json:"duration"`
+	Remaining string   `json:"remaining,omitempty"`
+	Priority  string   `json:"priority"`
+	Tags      []string `json:"tags,omitempty"`
+	Project   string   `json:"project,omitempty"`
+}
+
+func newTaskJSON(id int, task Task) taskJSON {
+	return taskJSON{
+		ID:        id,
+		Name:      task.Name,
+		Duration:  task.Duration.String(),
+		Remaining: task.Remaining.Round(time.Second).String(),
+		Priority:  priorityString(task.Priority),
+		Tags:      task.Tags,
+		Project:   task.Project,
+	}
+}
+
+// server exposes a Timer over HTTP. Task ids are the 1-based queue
+// positions shown by the list command.
+type server struct {
+	timer *Timer
+	hub   *hub
+}
+
+func newServer(t *Timer) http.Handler {
+	s := &server{timer: t, hub: newHub()}
+	go broadcastTicks(t, s.hub)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /tasks", s.addTask)
+	mux.HandleFunc("GET /tasks", s.listTasks)
+	mux.HandleFunc("DELETE /tasks/{id}", s.removeTask)
+	mux.HandleFunc("GET /tasks/current", s.currentTask)
+	mux.HandleFunc("POST /tasks/current/pause", s.pause)
+	mux.HandleFunc("POST /tasks/current/resume", s.resume)
+	mux.HandleFunc("GET /history", s.history)
+	mux.HandleFunc("GET /ws", s.websocket)
+	return mux
+}
+
+func (s *server) addTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string   `json:"name"`
+		Duration string   `json:"duration"`
+		Priority string   `json:"priority"`
+		Tags     []string `json:"tags"`
+		Project  string   `json:"project"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("name is required"))
+		return
+	}
+	if err := checkName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	duration, err := parseDuration(req.Duration)
+	var parseErr *DurationParseError
+	if errors.As(err, &parseErr) {
+		err = fmt.Errorf("duration %q: %s", parseErr.Input, parseErr.Reason)
+	}
+	if err == nil && duration <= 0 {
+		err = errors.New("duration must be positive")
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	priority, err := parsePriority(req.Priority)
+	if err == nil {
+		err = checkTags(req.Tags)
+	}
+	if err == nil {
+		err = checkProject(req.Project)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task := Task{Name: req.Name, Duration: duration, Remaining: duration, Priority: priority, Tags: req.Tags, Project: req.Project}
+	if err := s.timer.CheckTask(task); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.timer.Add(task)
+	writeJSON(w, http.StatusCreated, newTaskJSON(0, task))
+}
+
+func (s *server) listTasks(w http.ResponseWriter, r *http.Request) {
+	tasks := []taskJSON{}
+	for i, task := range s.timer.Queue() {
+		tasks = append(tasks, newTaskJSON(i+1, task))
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (s *server) removeTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid task id"))
+		return
+	}
+
+	task, err := s.timer.Remove(id - 1)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskJSON(id, task))
+}
+
+func (s *server) currentTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.timer.Current()
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrNotRunning)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskJSON(0, task))
+}
+
+func (s *server) pause(w http.ResponseWriter, r *http.Request) {
+	s.control(w, s.timer.Pause())
+}
+
+func (s *server) resume(w http.ResponseWriter, r *http.Request) {
+	s.control(w, s.timer.Resume())
+}
+
+// control answers a pause or resume request with the running task.
+func (s *server) control(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotRunning):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusConflict, err)
+	default:
+		s.currentTask(w, nil)
+	}
+}
+
+func (s *server) history(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.timer.History()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
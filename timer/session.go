@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Session covers the tasks run from the moment the timer picks one up
+// with an empty queue until the queue has run dry again.
+type Session struct {
+	// Start is when the first task was picked up and End is when the
+	// last one stopped running.
+	Start, End time.Time
+	Completed  int
+	// Planned is the sum of the durations of every task run, however it
+	// ended.
+	Planned   time.Duration
+	Skipped   []string
+	Cancelled []string
+}
+
+// record adds a task that stopped running to the session.
+func (s *Session) record(task Task, completed, skipped bool) {
+	s.End = time.Now()
+	s.Planned += task.Duration
+	switch {
+	case completed:
+		s.Completed++
+	case skipped:
+		s.Skipped = append(s.Skipped, task.Name)
+	default:
+		s.Cancelled = append(s.Cancelled, task.Name)
+	}
+}
+
+// printSessionReport is the --session-report summary of a session.
+func printSessionReport(s Session) {
+	fmt.Println("\nSession report")
+	fmt.Printf("  Completed: %d task(s)\n", s.Completed)
+	fmt.Printf("  Planned:   %s\n", shortDuration(s.Planned.Round(time.Second)))
+	fmt.Printf("  Actual:    %s\n", shortDuration(s.End.Sub(s.Start).Round(time.Second)))
+	if len(s.Skipped) > 0 {
+		fmt.Printf("  Skipped:   %s\n", strings.Join(s.Skipped, ", "))
+	}
+	if len(s.Cancelled) > 0 {
+		fmt.Printf("  Cancelled: %s\n", strings.Join(s.Cancelled, ", "))
+	}
+}
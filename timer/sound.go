@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// bellInterval spaces out repeated bells so terminals don't merge them.
+const bellInterval = 300 * time.Millisecond
+
+var (
+	// soundEnabled is set by --sound; alertSoundFile by --sound-file.
+	soundEnabled   bool
+	alertSoundFile string
+
+	// bellEnabled is set by --bell; bellCount by --repeat-bell.
+	bellEnabled bool
+	bellCount   = 1
+
+	errNoPlayer = errors.New("no audio player available")
+)
+
+// playAlert plays soundFile with the platform's audio player, falling back
+// to the terminal bell when there is no file or no player.
+func playAlert(soundFile string) {
+	if !soundEnabled {
+		return
+	}
+
+	go func() {
+		err := playSound(soundFile)
+		if err == nil {
+			return
+		}
+		if soundFile != "" && !errors.Is(err, errNoPlayer) {
+			slog.Warn("cannot play sound", "file", soundFile, "err", err)
+		}
+		fmt.Print("\a")
+	}()
+}
+
+// ringBell rings the terminal bell bellCount times.
+func ringBell() {
+	if !bellEnabled {
+		return
+	}
+
+	go func() {
+		for i := 0; i < bellCount; i++ {
+			if i > 0 {
+				time.Sleep(bellInterval)
+			}
+			fmt.Print("\a")
+		}
+	}()
+}
@@ -0,0 +1,12 @@
+//go:build darwin
+
+package main
+
+import "os/exec"
+
+func playSound(soundFile string) error {
+	if soundFile == "" {
+		return errNoPlayer
+	}
+	return exec.Command("afplay", soundFile).Run()
+}
@@ -0,0 +1,17 @@
+//go:build linux
+
+package main
+
+import "os/exec"
+
+func playSound(soundFile string) error {
+	if soundFile == "" {
+		return errNoPlayer
+	}
+	for _, player := range []string{"paplay", "aplay"} {
+		if path, err := exec.LookPath(player); err == nil {
+			return exec.Command(path, soundFile).Run()
+		}
+	}
+	return errNoPlayer
+}
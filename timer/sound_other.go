@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+func playSound(soundFile string) error {
+	return errNoPlayer
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func playSound(soundFile string) error {
+	script := "[console]::beep(880, 300)"
+	if soundFile != "" {
+		quoted := "'" + strings.ReplaceAll(soundFile, "'", "''") + "'"
+		script = fmt.Sprintf("(New-Object Media.SoundPlayer %s).PlaySync()", quoted)
+	}
+	return exec.Command("powershell", "-NoProfile", "-Command", script).Run()
+}
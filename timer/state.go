@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const defaultStateFile = "timer_state.json"
+
+// saveState writes the running tasks, with the time they have left, to
+// Config.StateFile so they can be resumed if the process is killed. The
+// file is removed once nothing is running or the timer is stopped.
+func (t *Timer) saveState() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.Config.StateFile == "" {
+		return
+	}
+	select {
+	case <-t.stop:
+		return
+	default:
+	}
+	if len(t.active) == 0 {
+		t.removeState()
+		return
+	}
+
+	t.writeState(t.runningTasks())
+}
+
+// runningTasks returns copies of the running tasks. The caller must hold
+// t.mu.
+func (t *Timer) runningTasks() []Task {
+	tasks := make([]Task, 0, len(t.active))
+	for _, active := range t.active {
+		tasks = append(tasks, *active.task)
+	}
+	return tasks
+}
+
+func (t *Timer) writeState(tasks []Task) {
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err == nil {
+		err = os.WriteFile(t.Config.StateFile, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("cannot save running tasks", "file", t.Config.StateFile, "err", err)
+	}
+}
+
+// Interrupt stops the timer like Stop, for a signal asking the process
+// to end. The running tasks are paused first and recorded in history as
+// interrupted, with the time they ran, and left in Config.StateFile to
+// be offered for resuming at the next start. The time recorded is kept
+// in Task.Logged, so that a resumed task's history only adds the rest.
+func (t *Timer) Interrupt() {
+	t.Pause()
+
+	t.mu.Lock()
+	tasks := t.runningTasks()
+	// ran is the time each task ran that is not yet in history.
+	ran := make([]time.Duration, len(tasks))
+	for i := range tasks {
+		ran[i] = tasks[i].Duration - tasks[i].Remaining - tasks[i].Logged
+		tasks[i].Logged += ran[i]
+	}
+	if t.Config.StateFile != "" && len(tasks) > 0 {
+		t.writeState(tasks)
+	}
+	if t.stop != nil {
+		select {
+		case <-t.stop:
+		default:
+			close(t.stop)
+		}
+	}
+	for _, active := range t.active {
+		active.cancel(nil)
+	}
+	t.mu.Unlock()
+
+	t.historyMu.Lock()
+	defer t.historyMu.Unlock()
+	for i, task := range tasks {
+		slog.Info("task interrupted", "task", task.Name, "remaining", task.Remaining.Round(time.Second))
+		entry := HistoryEntry{
+			Name:        task.Name,
+			Duration:    ran[i].Round(time.Second),
+			CompletedAt: time.Now(),
+			Status:      StatusInterrupted,
+			Tags:        task.Tags,
+			Project:     task.Project,
+			Notes:       task.Notes,
+		}
+		if err := logHistory(t.Config, entry); err != nil {
+			slog.Warn("cannot log history", "file", t.Config.HistoryFile, "err", err)
+			continue
+		}
+		if t.OnHistory != nil {
+			t.OnHistory(entry)
+		}
+	}
+}
+
+// removeState deletes the state file. The caller must hold t.mu.
+func (t *Timer) removeState() {
+	if t.Config.StateFile == "" {
+		return
+	}
+	if err := os.Remove(t.Config.StateFile); err != nil && !os.IsNotExist(err) {
+		slog.Warn("cannot remove running tasks", "file", t.Config.StateFile, "err", err)
+	}
+}
+
+// loadState returns the tasks saveState found running when the process
+// last died, if any.
+func loadState(path string) ([]Task, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tasks, nil
+}
+
+// offerResume asks, one task at a time, whether each interrupted task
+// should run next with the time it had left.
+func offerResume(t *Timer, tasks []Task) {
+	if len(tasks) == 0 {
+		return
+	}
+
+	task := tasks[0]
+	fmt.Printf("Interrupted: %s %s remaining. Resume? [y/N] ", task.Name, task.Remaining.Round(time.Second))
+	confirmation = func(yes bool) {
+		if yes {
+			t.Insert(0, task)
+			fmt.Printf("Resuming task: %s\n", task.Name)
+		}
+		offerResume(t, tasks[1:])
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// showStats prints per-task totals, counts, averages and extremes, followed
+// by the overall total and the average per active day.
+func showStats(entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		fmt.Println("No history available")
+		return nil
+	}
+
+	byName := make(map[string][]time.Duration)
+	days := make(map[string]bool)
+	var total time.Duration
+	for _, e := range entries {
+		byName[e.Name] = append(byName[e.Name], e.Duration)
+		days[e.CompletedAt.Format("2006-01-02")] = true
+		total += e.Duration
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Task\tCount\tTotal\tAverage\tMin\tMax")
+	for _, name := range names {
+		durations := byName[name]
+		var sum time.Duration
+		lo, hi := durations[0], durations[0]
+		for _, d := range durations {
+			sum += d
+			lo = min(lo, d)
+			hi = max(hi, d)
+		}
+		avg := sum / time.Duration(len(durations))
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			name, len(durations), sum, avg.Round(time.Second), lo, hi)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nTotal: %s across %d task(s)\n", total, len(entries))
+	fmt.Printf("Daily average: %s over %d day(s)\n",
+		(total / time.Duration(len(days))).Round(time.Second), len(days))
+	return nil
+}
+
+// showStreak prints the current run of consecutive days with a completed
+// task, ending today or, while today is still empty, yesterday, and the
+// longest such run in the history.
+func showStreak(entries []HistoryEntry, now time.Time) {
+	days := make(map[string]bool)
+	for _, e := range entries {
+		if e.Status == StatusCompleted {
+			days[e.CompletedAt.Local().Format(time.DateOnly)] = true
+		}
+	}
+	if len(days) == 0 {
+		fmt.Println("No completed tasks yet")
+		return
+	}
+
+	day := func(t time.Time, offset int) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, time.Local)
+	}
+	done := func(t time.Time) bool {
+		return days[t.Format(time.DateOnly)]
+	}
+
+	end := day(now, 0)
+	if !done(end) {
+		end = day(end, -1)
+	}
+	current := 0
+	for done(day(end, -current)) {
+		current++
+	}
+	start := day(end, 1-current)
+
+	longest := 0
+	for key := range days {
+		d, _ := time.ParseInLocation(time.DateOnly, key, time.Local)
+		if done(day(d, -1)) {
+			continue // not the first day of a run
+		}
+		n := 1
+		for done(day(d, n)) {
+			n++
+		}
+		longest = max(longest, n)
+	}
+
+	switch current {
+	case 0:
+		fmt.Println("Current streak: 0 days")
+	case 1:
+		fmt.Printf("Current streak: 1 day (%s)\n", end.Format("Mon"))
+	default:
+		fmt.Printf("Current streak: %d days (%s\u2013%s)\n", current, start.Format("Mon"), end.Format("Mon"))
+	}
+	if longest == 1 {
+		fmt.Println("Longest streak: 1 day")
+		return
+	}
+	fmt.Printf("Longest streak: %d days\n", longest)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// syncRetries is how many times a failed POST is retried, waiting
+	// syncBackoff, then twice as long, and so on, between attempts.
+	syncRetries = 3
+	syncBackoff = time.Second
+	// syncFlushTimeout bounds how long exit waits for queued entries.
+	syncFlushTimeout = 5 * time.Second
+)
+
+// historySync POSTs history entries as JSON to a URL from a background
+// goroutine, so the timer never waits on the network.
+type historySync struct {
+	url    string
+	client *http.Client
+	done   chan struct{}
+
+	// mu guards pending against send racing close.
+	mu      sync.Mutex
+	closed  bool
+	pending chan HistoryEntry
+}
+
+func newHistorySync(url string) *historySync {
+	s := &historySync{
+		url:     url,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: make(chan HistoryEntry, 100),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// send queues entry for upload. If the queue is full the entry is
+// dropped and a warning logged.
+func (s *historySync) send(entry HistoryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	select {
+	case s.pending <- entry:
+	default:
+		slog.Warn("history sync queue full, dropping entry", "task", entry.Name)
+	}
+}
+
+// close waits a little for the queued entries to be sent.
+func (s *historySync) close() {
+	s.mu.Lock()
+	s.closed = true
+	close(s.pending)
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-time.After(syncFlushTimeout):
+		slog.Warn("gave up waiting for history sync to finish", "url", s.url)
+	}
+}
+
+func (s *historySync) run() {
+	defer close(s.done)
+	slog.Debug("history sync goroutine started")
+	for entry := range s.pending {
+		backoff := syncBackoff
+		err := s.post(entry)
+		for retry := 1; err != nil && retry <= syncRetries; retry++ {
+			time.Sleep(backoff)
+			backoff *= 2
+			err = s.post(entry)
+		}
+		if err != nil {
+			slog.Warn("cannot sync history entry", "task", entry.Name, "url", s.url, "err", err)
+		}
+	}
+}
+
+func (s *historySync) post(entry HistoryEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("server replied %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// docKind is the shape of a docNode.
+type docKind int
+
+const (
+	docScalar docKind = iota
+	docList
+	docMap
+)
+
+// docNode is a value of a YAML or TOML document: a scalar, a list of
+// items or a mapping of keys, in order, to values. Line is where it
+// starts, for error messages.
+type docNode struct {
+	Kind   docKind
+	Line   int
+	Value  string
+	Items  []*docNode
+	Keys   []string
+	Values map[string]*docNode
+}
+
+// lineError is an error at a line of a task file.
+type lineError struct {
+	Line int
+	Msg  string
+}
+
+func (e *lineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// loadTaskFiles queues the tasks of every file matching pattern in
+// alphabetical order. A bad pattern or one matching nothing is only
+// warned about; an invalid file stops the loading.
+func loadTaskFiles(t *Timer, pattern string) error {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		slog.Warn("invalid task file pattern", "pattern", pattern, "err", err)
+		return nil
+	}
+	if len(paths) == 0 {
+		slog.Warn("no task files match", "pattern", pattern)
+		return nil
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		n, err := loadTaskFile(t, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Loaded %d task(s) from %s\n", n, path)
+	}
+	return nil
+}
+
+// loadTaskFile queues the tasks listed in path. Nothing is queued if
+// any task is invalid.
+func loadTaskFile(t *Timer, path string) (int, error) {
+	tasks, err := readTaskFile(t, path)
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		t.Add(task)
+	}
+	return len(tasks), nil
+}
+
+// readTaskFile returns the tasks listed in path, in the format its
+// extension names: YAML for .yaml and .yml, TOML for .toml, and add
+// commands for .timer, .txt or no extension. Every invalid task is
+// reported, each error on its own line; a syntax error stops the reading.
+func readTaskFile(t *Timer, path string) ([]Task, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return readYAMLTasks(t, file, path)
+	case ".toml":
+		root, err := parseTOML(file)
+		if err != nil {
+			return nil, atLine(path, err, 0)
+		}
+		return docTasks(t, root, path)
+	case ".timer", ".txt", "":
+		return readCommandTasks(t, file, path)
+	default:
+		return nil, fmt.Errorf("%s: unknown task file extension %q (want .timer or .txt for add commands, .yaml, .yml or .toml)", path, ext)
+	}
+}
+
+// readCommandTasks reads a file of add commands, one per line, with or
+// without the leading "add". Blank lines and lines starting with # are
+// ignored.
+func readCommandTasks(t *Timer, file io.Reader, path string) ([]Task, error) {
+	var tasks []Task
+	var errs []error
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields, err := splitCommand(line)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", path, n, err))
+			continue
+		}
+		if strings.EqualFold(fields[0], "add") {
+			fields = fields[1:]
+		}
+		task, err := parseTask(fields, !t.Config.CountUp)
+		if err == nil {
+			err = t.checkTask(task, len(tasks))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", path, n, err))
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, errors.Join(errs...)
+}
+
+// loadYAMLTasks queues the tasks of a YAML document read from r, which is
+// called name in errors:
+//
+//	tasks:
+//	  - name: Study
+//	    duration: 25m
+//	    tags: [focus]
+//	    repeat: 4
+//	    priority: high
+//
+// The TOML form of the same list is an array of tables:
+//
+//	[[tasks]]
+//	name = "Study"
+//	duration = "25m"
+//	tags = ["focus"]
+//	repeat = 4
+func loadYAMLTasks(t *Timer, r io.Reader, name string) (int, error) {
+	tasks, err := readYAMLTasks(t, r, name)
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		t.Add(task)
+	}
+	return len(tasks), nil
+}
+
+func readYAMLTasks(t *Timer, r io.Reader, name string) ([]Task, error) {
+	root, err := parseYAML(r)
+	if err != nil {
+		return nil, atLine(name, err, 0)
+	}
+	return docTasks(t, root, name)
+}
+
+// docTasks returns the tasks of a parsed YAML or TOML document, which
+// may be a "tasks" list, the list alone, or a single task. repeat may be
+// "forever". Every problem with every task is reported.
+func docTasks(t *Timer, root *docNode, name string) ([]Task, error) {
+	list := root
+	if root.Kind == docMap {
+		if tasks, ok := root.Values["tasks"]; ok && len(root.Keys) == 1 {
+			list = tasks
+		} else {
+			list = &docNode{Kind: docList, Line: root.Line, Items: []*docNode{root}}
+		}
+	}
+	if list.Kind != docList {
+		return nil, atLine(name, errors.New("tasks must be a list"), list.Line)
+	}
+
+	var tasks []Task
+	var errs []error
+	for _, item := range list.Items {
+		task, taskErrs := taskFromDoc(item, !t.Config.CountUp)
+		if err := t.checkTask(task, len(tasks)); err != nil {
+			taskErrs = append(taskErrs, err)
+		}
+		for _, err := range taskErrs {
+			errs = append(errs, atLine(name, err, item.Line))
+		}
+		if len(taskErrs) == 0 {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, errors.Join(errs...)
+}
+
+// atLine puts the name of a task file and the line of the problem, if
+// known, in front of err.
+func atLine(name string, err error, line int) error {
+	var lineErr *lineError
+	if errors.As(err, &lineErr) {
+		return fmt.Errorf("%s:%d: %s", name, lineErr.Line, lineErr.Msg)
+	}
+	if line == 0 {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return fmt.Errorf("%s:%d: %w", name, line, err)
+}
+
+// taskFromDoc builds a task from a mapping of a task list, returning
+// every problem with its fields. The duration may be left out unless
+// needDuration is set.
+func taskFromDoc(node *docNode, needDuration bool) (Task, []error) {
+	if node.Kind != docMap {
+		return Task{}, []error{errors.New("a task must be a mapping with at least a name")}
+	}
+
+	var task Task
+	var errs []error
+	for _, key := range node.Keys {
+		value := node.Values[key]
+		if key != "tags" && value.Kind != docScalar {
+			errs = append(errs, &lineError{value.Line, fmt.Sprintf("%s must be a single value", key)})
+			continue
+		}
+
+		var err error
+		switch key {
+		case "name":
+			task.Name = strings.TrimSpace(value.Value)
+			err = checkName(task.Name)
+		case "duration":
+			task.Duration, err = parseDuration(value.Value)
+			if err == nil && task.Duration <= 0 {
+				err = errors.New("duration must be positive")
+			}
+		case "tags":
+			task.Tags, err = docLabels(value)
+			if err == nil {
+				err = checkTags(task.Tags)
+			}
+		case "repeat":
+			if strings.EqualFold(value.Value, "forever") {
+				task.RepeatForever = true
+				break
+			}
+			task.RepeatCount, err = strconv.Atoi(value.Value)
+			if err != nil || task.RepeatCount < 1 {
+				err = fmt.Errorf("repeat needs a positive count or \"forever\", got %q", value.Value)
+			}
+		case "priority":
+			task.Priority, err = parsePriority(value.Value)
+		case "project":
+			task.Project = strings.TrimSpace(value.Value)
+			err = checkProject(task.Project)
+		default:
+			err = fmt.Errorf("unknown field %q (want name, duration, tags, project, repeat or priority)", key)
+		}
+		if err != nil {
+			errs = append(errs, &lineError{value.Line, err.Error()})
+		}
+	}
+
+	if task.Name == "" {
+		errs = append(errs, errors.New("task has no name"))
+	}
+	if _, ok := node.Values["duration"]; !ok && needDuration {
+		errs = append(errs, fmt.Errorf("task %q has no duration", task.Name))
+	}
+	return task, errs
+}
+
+// docLabels reads tags given as a list or as one space-separated string.
+func docLabels(node *docNode) ([]string, error) {
+	if node.Kind == docScalar {
+		return strings.Fields(node.Value), nil
+	}
+	var labels []string
+	for _, item := range node.Items {
+		if item.Kind != docScalar {
+			return nil, errors.New("tags must be a list of labels")
+		}
+		labels = append(labels, item.Value)
+	}
+	if node.Kind != docList {
+		return nil, errors.New("tags must be a list of labels")
+	}
+	return labels, nil
+}
+
+// validateTaskFiles checks the task files in paths without queueing
+// anything, printing every problem found, and reports whether all of
+// them are valid.
+func validateTaskFiles(t *Timer, paths []string) bool {
+	valid := true
+	for _, path := range paths {
+		tasks, err := readTaskFile(t, path)
+		if err != nil {
+			valid = false
+			count := 1
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				count = len(joined.Unwrap())
+			}
+			fmt.Println(err)
+			fmt.Printf("%s: %d error(s)\n", path, count)
+			continue
+		}
+		fmt.Printf("%s: %d task(s), no errors\n", path, len(tasks))
+	}
+	return valid
+}
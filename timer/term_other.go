@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoTerminal = errors.New("the full-screen interface is not supported on this platform")
+
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errNoTerminal
+}
+
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errNoTerminal
+}
+
+func notifyResize(ch chan<- os.Signal) {}
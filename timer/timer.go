@@ -0,0 +1,1169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Task is a named countdown.
+type Task struct {
+	Name      string
+	Duration  time.Duration
+	Remaining time.Duration
+	Priority  int
+
+	// RepeatCount is how many more runs are due, including the current
+	// one; RepeatForever requeues the task after every run.
+	RepeatCount   int
+	RepeatForever bool
+
+	// Tags are free-form labels for grouping tasks, recorded in history.
+	Tags []string `json:",omitempty"`
+	// Project is the one project the task belongs to, if any, recorded
+	// in history for per-project reports.
+	Project string `json:",omitempty"`
+
+	// StartAt, if set, holds the task back until that wall-clock time
+	// once it reaches the front of the queue.
+	StartAt time.Time `json:",omitzero"`
+
+	// Notes are what the user wrote about the run with the note command,
+	// one note per line. They are recorded in history.
+	Notes string `json:",omitempty"`
+
+	// Logged is how much of the run is already in history, recorded as
+	// interrupted, so that resuming the task does not count it twice.
+	Logged time.Duration `json:",omitzero"`
+}
+
+// Task priorities. Higher priorities run first; the zero value is normal.
+const (
+	PriorityLow    = -1
+	PriorityNormal = 0
+	PriorityHigh   = 1
+)
+
+var (
+	// errSkipped is the cancellation cause that distinguishes skip from
+	// cancel: skipped tasks are still recorded in history.
+	errSkipped = errors.New("skipped")
+	// errDone is the cancellation cause with which Done completes a task
+	// counting up.
+	errDone = errors.New("done")
+
+	ErrStarted       = errors.New("timer already started")
+	ErrNotRunning    = errors.New("no timer is running")
+	ErrAlreadyPaused = errors.New("timer is already paused")
+	ErrNotPaused     = errors.New("timer is not paused")
+	ErrNotCountingUp = errors.New("only tasks counting up (--count-up) are finished with done")
+)
+
+// Config holds the settings a Timer is created with.
+type Config struct {
+	// HistoryFile receives one line per completed task.
+	HistoryFile string
+	// LogFormat is how new history lines are written: LogFormatPipe (the
+	// default when empty) or LogFormatJSONL.
+	LogFormat string
+	// MaxLogSize is the size in bytes at which the history file is
+	// rotated, keeping MaxLogBackups old files, at least one. A zero
+	// MaxLogSize disables rotation.
+	MaxLogSize    int64
+	MaxLogBackups int
+	// QueueFile keeps the pending queue on disk when set.
+	QueueFile string
+	// StateFile, if set, holds the running tasks while they run so they
+	// can be resumed after a crash.
+	StateFile string
+	// Pomodoro refills the queue whenever it runs dry when set.
+	Pomodoro *PomodoroSchedule
+	// Parallel is how many tasks may run at once; values below 1 mean 1.
+	Parallel int
+	// MaxTaskDuration, if positive, is the longest task CheckTask accepts.
+	MaxTaskDuration time.Duration
+	// MaxQueueDepth, if positive, is how many pending tasks CheckTask
+	// allows before it rejects more. Add warns once the queue is 80% full.
+	MaxQueueDepth int
+	// CountUp shows the time spent on each task instead of a countdown;
+	// tasks run until Done is called and need no duration.
+	CountUp bool
+	// ShortestFirst orders tasks of the same priority by the time they
+	// have remaining, so the shortest job runs next.
+	ShortestFirst bool
+	// Output, if set, receives the countdown in place of stdout. Only
+	// completions, skips and cancellations are still printed.
+	Output io.Writer
+	// TickInterval is how often a running task is redrawn; zero means
+	// once a second.
+	TickInterval time.Duration
+	// CountdownStyle is how a running task is drawn: CountdownText (the
+	// default when empty), CountdownBar or CountdownSpinner.
+	CountdownStyle string
+	// BarWidth is the width of CountdownBar in columns; values below 1
+	// mean defaultBarWidth.
+	BarWidth int
+	// BeepAt lists the percentages of a countdown, in ascending order, at
+	// which OnMilestone is called.
+	BeepAt []int
+}
+
+// Timer counts down queued tasks one after another. Its methods are safe
+// to call from any goroutine while Start is running.
+type Timer struct {
+	Config Config
+	// OnComplete, if set, is called after a task runs to completion.
+	OnComplete func(Task)
+	// OnHistory, if set, is called with every entry written to the
+	// history file.
+	OnHistory func(HistoryEntry)
+	// OnMilestone, if set, is called as a countdown passes each of the
+	// Config.BeepAt percentages.
+	OnMilestone func(task Task, percent int)
+	// OnSessionEnd, if set, is called once the queue has run dry and no
+	// task is left running. It is never called in Pomodoro mode, whose
+	// queue refills itself.
+	OnSessionEnd func(Session)
+	// Tracer, if set, records a span for every task run and for writing
+	// its history entry.
+	Tracer *Tracer
+
+	mu     debugMutex
+	queue  []Task
+	active []*activeTask
+	wake   chan struct{}
+	stop   chan struct{}
+	// lastBusy is when a task last stopped running.
+	lastBusy time.Time
+	session  Session
+	// subscribers receive the events passed to emit.
+	subscribers map[chan<- Event]struct{}
+
+	// outMu keeps concurrent timers from interleaving their output and
+	// historyMu serialises writes to the history file.
+	outMu     debugMutex
+	historyMu debugMutex
+}
+
+// activeTask is the task being counted down and the controls for it.
+type activeTask struct {
+	task   *Task
+	slot   int
+	cancel context.CancelCauseFunc
+	paused bool
+	// pauseCh signals that paused changed.
+	pauseCh chan struct{}
+	// extendCh carries changes to the end time of the countdown.
+	extendCh chan time.Duration
+}
+
+// NewTimer returns an idle timer with an empty queue.
+func NewTimer(config Config) *Timer {
+	if config.HistoryFile == "" {
+		config.HistoryFile = defaultHistoryFile
+	}
+	return &Timer{
+		Config:    config,
+		mu:        debugMutex{name: "timer"},
+		outMu:     debugMutex{name: "output"},
+		historyMu: debugMutex{name: "history"},
+		wake:      make(chan struct{}, 1),
+		lastBusy:  time.Now(),
+	}
+}
+
+// Start runs queued tasks until Stop is called, waiting for new tasks
+// whenever the queue is empty. Up to Config.Parallel tasks run at once.
+func (t *Timer) Start() error {
+	t.mu.Lock()
+	if t.stop != nil {
+		t.mu.Unlock()
+		return ErrStarted
+	}
+	stop := make(chan struct{})
+	t.stop = stop
+	t.mu.Unlock()
+
+	// slots doubles as the semaphore capping parallelism and as the pool
+	// of display lines a running task may draw on.
+	slots := make(chan int, t.parallel())
+	for i := 0; i < cap(slots); i++ {
+		slots <- i
+	}
+	if cap(slots) > 1 && t.Config.Output == nil {
+		fmt.Print(strings.Repeat("\n", cap(slots)))
+	}
+
+	slog.Debug("timer loop started", "parallel", cap(slots))
+	defer slog.Debug("timer loop stopped")
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		var slot int
+		select {
+		case slot = <-slots:
+		case <-stop:
+			return nil
+		}
+
+		ctx, active, ok := t.next(slot)
+		if !ok {
+			slots <- slot
+			select {
+			case <-t.wake:
+				continue
+			case <-stop:
+				return nil
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Debug("task goroutine started", "task", active.task.Name, "slot", active.slot)
+			t.run(ctx, active)
+			slog.Debug("task goroutine finished", "task", active.task.Name, "slot", active.slot)
+			slots <- active.slot
+		}()
+	}
+}
+
+func (t *Timer) tickInterval() time.Duration {
+	if t.Config.TickInterval <= 0 {
+		return time.Second
+	}
+	return t.Config.TickInterval
+}
+
+func (t *Timer) parallel() int {
+	if t.Config.Parallel < 1 {
+		return 1
+	}
+	return t.Config.Parallel
+}
+
+// Stop cancels the running task and makes Start return.
+func (t *Timer) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stop != nil {
+		select {
+		case <-t.stop:
+		default:
+			close(t.stop)
+		}
+	}
+	for _, active := range t.active {
+		active.cancel(nil)
+	}
+	t.removeState()
+}
+
+// next pops the following task and makes it active in the given display
+// slot. It reports false when there is nothing to run or the timer has
+// been stopped.
+func (t *Timer) next(slot int) (context.Context, *activeTask, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.stop:
+		return nil, nil, false
+	default:
+	}
+
+	if len(t.queue) == 0 && t.Config.Pomodoro != nil {
+		t.queue = append(t.queue, t.Config.Pomodoro.next())
+	}
+	if len(t.queue) == 0 {
+		return nil, nil, false
+	}
+
+	task := t.queue[0]
+	t.queue = t.queue[1:]
+	t.persist()
+	if t.session.Start.IsZero() {
+		t.session.Start = time.Now()
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	active := &activeTask{
+		task:     &task,
+		slot:     slot,
+		cancel:   cancel,
+		pauseCh:  make(chan struct{}, 1),
+		extendCh: make(chan time.Duration, 8),
+	}
+	t.active = append(t.active, active)
+	return ctx, active, true
+}
+
+// run counts the active task down and records it if it completes.
+func (t *Timer) run(ctx context.Context, active *activeTask) {
+	defer t.endSession()
+
+	span := t.Tracer.Start("task", nil)
+	completed := t.startTimer(ctx, active)
+	skipped := errors.Is(context.Cause(ctx), errSkipped)
+	active.cancel(nil)
+
+	t.mu.Lock()
+	for i, a := range t.active {
+		if a == active {
+			t.active = append(t.active[:i], t.active[i+1:]...)
+			break
+		}
+	}
+	t.lastBusy = time.Now()
+	task := *active.task
+	t.session.record(task, completed, skipped)
+	t.mu.Unlock()
+	t.saveState()
+
+	entry := HistoryEntry{
+		Name:        task.Name,
+		Duration:    (task.Duration - task.Logged).Round(time.Second),
+		CompletedAt: time.Now(),
+		Status:      StatusCompleted,
+		Tags:        task.Tags,
+		Project:     task.Project,
+		Notes:       task.Notes,
+	}
+	switch {
+	case completed:
+		slog.Info("task completed", "task", task.Name, "duration", task.Duration.Round(time.Second))
+		t.emit(EventTaskCompleted, &task)
+	case skipped:
+		slog.Info("task skipped", "task", task.Name, "remaining", task.Remaining.Round(time.Second))
+		t.emit(EventTaskSkipped, &task)
+	default:
+		slog.Info("task cancelled", "task", task.Name, "remaining", task.Remaining.Round(time.Second))
+		t.emit(EventTaskCancelled, &task)
+	}
+	span.SetString("task.name", task.Name)
+	span.SetInt("task.duration_seconds", int64(task.Duration.Round(time.Second)/time.Second))
+	switch {
+	case completed:
+		span.SetString("task.status", StatusCompleted)
+	case skipped:
+		span.SetString("task.status", StatusSkipped)
+	default:
+		span.SetString("task.status", statusCancelled)
+	}
+	span.End()
+
+	switch {
+	case completed:
+		if t.OnComplete != nil {
+			t.OnComplete(task)
+		}
+	case skipped:
+		entry.Duration = (task.Duration - task.Remaining - task.Logged).Round(time.Second)
+		entry.Status = StatusSkipped
+	default:
+		// Cancelled tasks never finished, so they stay out of history.
+		return
+	}
+	t.requeue(task)
+
+	t.historyMu.Lock()
+	defer t.historyMu.Unlock()
+	historySpan := t.Tracer.Start("logHistory", span)
+	err := logHistory(t.Config, entry)
+	historySpan.SetError(err)
+	historySpan.End()
+	if err != nil {
+		slog.Warn("cannot log history", "file", t.Config.HistoryFile, "err", err)
+		return
+	}
+	if t.OnHistory != nil {
+		t.OnHistory(entry)
+	}
+}
+
+// endSession passes the session to OnSessionEnd and starts a new one
+// once the queue has run dry. Stopping the timer ends no session.
+func (t *Timer) endSession() {
+	t.mu.Lock()
+	session := t.session
+	over := len(t.queue) == 0 && len(t.active) == 0 && t.Config.Pomodoro == nil
+	select {
+	case <-t.stop:
+		over = false
+	default:
+	}
+	if over {
+		t.session = Session{}
+	}
+	t.mu.Unlock()
+
+	if over && t.OnSessionEnd != nil {
+		t.OnSessionEnd(session)
+	}
+	if over {
+		t.emit(EventQueueEmpty, nil)
+	}
+}
+
+// startTimer counts the task down and reports whether it ran to
+// completion. It returns false as soon as ctx is cancelled.
+func (t *Timer) startTimer(ctx context.Context, active *activeTask) bool {
+	task := active.task
+	waited := t.waitForStart(ctx, active)
+	endTime := time.Now().Add(task.Remaining)
+	// Ticks never overshoot the end time, however long the interval.
+	interval := t.tickInterval()
+	nextTick := func() time.Duration {
+		return max(min(interval, time.Until(endTime)), time.Millisecond)
+	}
+	precision := min(interval, time.Second)
+	ticker := time.NewTicker(nextTick())
+	defer ticker.Stop()
+	ticks := 0
+
+	// milestone is the next of Config.BeepAt to be reached; those a
+	// resumed task has already passed are not repeated.
+	milestone := 0
+	percentDone := func(remaining time.Duration) int {
+		if task.Duration <= 0 {
+			return 100
+		}
+		return int(100 * (task.Duration - remaining) / task.Duration)
+	}
+	for milestone < len(t.Config.BeepAt) && percentDone(task.Remaining) >= t.Config.BeepAt[milestone] {
+		milestone++
+	}
+
+	cancelled := func() bool {
+		remaining := max(time.Until(endTime), 0)
+		t.mu.Lock()
+		task.Remaining = remaining
+		t.mu.Unlock()
+
+		if errors.Is(context.Cause(ctx), errSkipped) {
+			t.announce(active.slot, fmt.Sprintf("\r%s: %s after %s\n",
+				t.name(task), colorize(colors.Warning, "Skipped"), (task.Duration-remaining).Round(time.Second)))
+			return false
+		}
+		t.announce(active.slot, fmt.Sprintf("\r%s: %s with %s remaining\n",
+			t.name(task), colorize(colors.Error, "Cancelled"), remaining.Round(time.Second)))
+		return false
+	}
+
+	if !waited {
+		return cancelled()
+	}
+	slog.Info("task started", "task", task.Name, "duration", task.Duration.Round(time.Second))
+	t.emit(EventTaskStarted, task)
+	if t.Config.CountUp {
+		return t.countUp(ctx, active)
+	}
+	t.render(active.slot, fmt.Sprintf("\nStarting %s timer for %s\n", t.name(task), task.Duration.Round(time.Second)))
+
+	completed := func() bool {
+		t.announce(active.slot, fmt.Sprintf("\r%s: %s\n", t.name(task), colorize(colors.Completed, "Completed!")))
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return cancelled()
+		case <-active.pauseCh:
+			if !t.isPaused(active) {
+				continue
+			}
+			t.mu.Lock()
+			task.Remaining = time.Until(endTime)
+			t.mu.Unlock()
+			t.saveState()
+			t.render(active.slot, fmt.Sprintf("\r%s: %s with %s remaining\n", t.name(task), colorize(colors.Paused, "paused"), task.Remaining.Round(time.Second)))
+
+			// Block until resumed so no ticks are consumed while paused.
+			for t.isPaused(active) {
+				select {
+				case <-active.pauseCh:
+				case delta := <-active.extendCh:
+					// Shortening never takes the countdown below zero.
+					delta = max(delta, -task.Remaining)
+					t.mu.Lock()
+					task.Duration += delta
+					task.Remaining += delta
+					t.mu.Unlock()
+					if task.Remaining <= 0 {
+						return completed()
+					}
+					t.render(active.slot, fmt.Sprintf("\r%s: %s with %s remaining\n", t.name(task), colorize(colors.Paused, "paused"), task.Remaining.Round(time.Second)))
+				case <-ctx.Done():
+					endTime = time.Now().Add(task.Remaining)
+					return cancelled()
+				}
+			}
+			endTime = time.Now().Add(task.Remaining)
+			ticker.Reset(nextTick())
+			t.render(active.slot, fmt.Sprintf("%s: resumed\n", t.name(task)))
+		case delta := <-active.extendCh:
+			// Shortening never moves the end time before now.
+			delta = max(delta, -time.Until(endTime))
+			endTime = endTime.Add(delta)
+			ticker.Reset(nextTick())
+			remaining := time.Until(endTime).Round(precision)
+			t.mu.Lock()
+			task.Duration += delta
+			task.Remaining = remaining
+			t.mu.Unlock()
+			if remaining <= 0 {
+				return completed()
+			}
+			t.render(active.slot, t.countdownLine(task, remaining, ticks))
+		case <-ticker.C:
+			ticks++
+			remaining := time.Until(endTime).Round(precision)
+			if verbose {
+				slog.Debug("ticker fired", "task", task.Name, "remaining", remaining)
+			}
+			t.mu.Lock()
+			task.Remaining = remaining
+			t.mu.Unlock()
+			if remaining <= 0 {
+				return completed()
+			}
+			t.emit(EventTick, task)
+			t.saveState()
+			for milestone < len(t.Config.BeepAt) && percentDone(remaining) >= t.Config.BeepAt[milestone] {
+				if t.OnMilestone != nil {
+					t.OnMilestone(*task, t.Config.BeepAt[milestone])
+				}
+				milestone++
+			}
+			ticker.Reset(nextTick())
+			t.render(active.slot, t.countdownLine(task, remaining, ticks))
+		}
+	}
+}
+
+// countUp shows the time spent on the task until Done is called, which
+// completes it, or ctx is cancelled otherwise. The task's Duration tracks
+// the elapsed time, excluding pauses, so that is what history records.
+func (t *Timer) countUp(ctx context.Context, active *activeTask) bool {
+	task := active.task
+	var elapsed time.Duration
+	started := time.Now()
+	paused := false
+	precision := min(t.tickInterval(), time.Second)
+	ticker := time.NewTicker(t.tickInterval())
+	defer ticker.Stop()
+
+	update := func() time.Duration {
+		total := elapsed
+		if !paused {
+			total += time.Since(started)
+		}
+		t.mu.Lock()
+		task.Duration = total
+		task.Remaining = 0
+		t.mu.Unlock()
+		return total.Round(precision)
+	}
+
+	t.render(active.slot, fmt.Sprintf("\nStarting %s, type 'done' when finished\n", t.name(task)))
+	for {
+		select {
+		case <-ctx.Done():
+			total := update()
+			switch cause := context.Cause(ctx); {
+			case errors.Is(cause, errDone):
+				t.announce(active.slot, fmt.Sprintf("\r%s: %s after %s\n", t.name(task), colorize(colors.Completed, "Completed"), total))
+				return true
+			case errors.Is(cause, errSkipped):
+				t.announce(active.slot, fmt.Sprintf("\r%s: %s after %s\n", t.name(task), colorize(colors.Warning, "Skipped"), total))
+			default:
+				t.announce(active.slot, fmt.Sprintf("\r%s: %s after %s\n", t.name(task), colorize(colors.Error, "Cancelled"), total))
+			}
+			return false
+		case <-active.pauseCh:
+			switch nowPaused := t.isPaused(active); {
+			case nowPaused && !paused:
+				elapsed += time.Since(started)
+				paused = true
+				t.render(active.slot, fmt.Sprintf("\r%s: %s at %s\n", t.name(task), colorize(colors.Paused, "paused"), update()))
+			case !nowPaused && paused:
+				started = time.Now()
+				paused = false
+				t.render(active.slot, fmt.Sprintf("%s: resumed\n", t.name(task)))
+			}
+		case <-ticker.C:
+			if verbose {
+				slog.Debug("ticker fired", "task", task.Name, "paused", paused)
+			}
+			if !paused {
+				t.render(active.slot, fmt.Sprintf("\r%s: %-10s elapsed", t.name(task), update()))
+				t.emit(EventTick, task)
+				t.saveState()
+			}
+		}
+	}
+}
+
+// waitForStart holds a scheduled task back until its StartAt time,
+// updating the waiting message every minute. It reports false if the task
+// was cancelled or skipped meanwhile.
+func (t *Timer) waitForStart(ctx context.Context, active *activeTask) bool {
+	t.mu.Lock()
+	startAt := active.task.StartAt
+	t.mu.Unlock()
+	if !time.Now().Before(startAt) {
+		return true
+	}
+
+	start := time.NewTimer(time.Until(startAt))
+	defer start.Stop()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		t.render(active.slot, fmt.Sprintf("\r%s: waiting until %s (%s to go)",
+			t.name(active.task), startAt.Format("15:04"), time.Until(startAt).Round(time.Minute)))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-start.C:
+			return true
+		case <-ticker.C:
+		}
+	}
+}
+
+// name reads the name of a running task, which Rename may change.
+func (t *Timer) name(task *Task) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return displayName(task)
+}
+
+// displayName is task's name as shown while it runs, with the tasks of
+// the Pomodoro schedule marked out. The caller must hold t.mu.
+func displayName(task *Task) string {
+	if slices.Contains(task.Tags, pomodoroTag) {
+		return pomodoroPrefix + " " + task.Name
+	}
+	return task.Name
+}
+
+// render prints text for the task drawing in slot. Running one task at a
+// time the text is printed as is; in parallel each slot redraws its own
+// line among those reserved by Start, leaving the cursor where it was.
+// With Config.Output set the text goes there instead.
+func (t *Timer) render(slot int, text string) {
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+
+	if t.Config.Output != nil {
+		fmt.Fprint(t.Config.Output, text)
+		return
+	}
+	n := t.parallel()
+	if n == 1 {
+		fmt.Print(text)
+		return
+	}
+	fmt.Printf("\0337\033[%dA\r\033[2K%s\0338", n-slot, strings.Trim(text, "\r\n"))
+}
+
+// announce renders text that the user should see even when progress is
+// sent to Config.Output, such as a task completing.
+func (t *Timer) announce(slot int, text string) {
+	if t.Config.Output == nil {
+		t.render(slot, text)
+		return
+	}
+
+	t.outMu.Lock()
+	defer t.outMu.Unlock()
+	fmt.Fprint(io.MultiWriter(os.Stdout, t.Config.Output), text)
+}
+
+// Add queues task behind every task of the same or higher priority, or
+// with Config.ShortestFirst behind every task that runs before it.
+//
+// The queue stays a sorted slice rather than a container/heap: list, move,
+// swap and the queue file all show it in the order it will run, which a
+// heap's array does not keep, and an insertion is cheap at queue sizes.
+func (t *Timer) Add(task Task) {
+	if task.Remaining <= 0 {
+		task.Remaining = task.Duration
+	}
+
+	t.mu.Lock()
+	i := len(t.queue)
+	for i > 0 && t.runsBefore(task, t.queue[i-1]) {
+		i--
+	}
+	t.queue = append(t.queue, Task{})
+	copy(t.queue[i+1:], t.queue[i:])
+	t.queue[i] = task
+	t.persist()
+	depth := len(t.queue)
+	t.mu.Unlock()
+
+	if limit := t.Config.MaxQueueDepth; limit > 0 && depth*5 >= limit*4 {
+		slog.Warn("queue is nearly full", "tasks", depth, "max", limit)
+	}
+	t.notify()
+}
+
+// requeue schedules the next run of a repeating task: at the front of the
+// queue for high priority tasks, behind its peers otherwise.
+func (t *Timer) requeue(task Task) {
+	if !task.RepeatForever {
+		task.RepeatCount--
+		if task.RepeatCount <= 0 {
+			return
+		}
+	}
+	task.Remaining = task.Duration
+	task.Logged = 0
+	// Repeats follow on straight away rather than waiting for the clock.
+	task.StartAt = time.Time{}
+	task.Notes = ""
+
+	if task.Priority < PriorityHigh {
+		t.Add(task)
+		return
+	}
+
+	t.mu.Lock()
+	t.queue = append([]Task{task}, t.queue...)
+	t.persist()
+	t.mu.Unlock()
+	t.notify()
+}
+
+// CheckTask reports whether task is acceptable under Config, such as
+// MaxTaskDuration, and whether the queue has room for it under
+// MaxQueueDepth. Add does not check; callers adding user input should.
+func (t *Timer) CheckTask(task Task) error {
+	return t.checkTask(task, 0)
+}
+
+// checkTask is CheckTask for a task that will be queued after ahead
+// others that are not queued yet, as when a task file is read.
+func (t *Timer) checkTask(task Task, ahead int) error {
+	if limit := t.Config.MaxTaskDuration; limit > 0 && task.Duration > limit {
+		return fmt.Errorf("Task duration %s exceeds maximum allowed %s", shortDuration(task.Duration), shortDuration(limit))
+	}
+	if limit := t.Config.MaxQueueDepth; limit > 0 {
+		t.mu.Lock()
+		depth := len(t.queue)
+		t.mu.Unlock()
+		switch {
+		case depth >= limit:
+			return fmt.Errorf("Queue is full: it already holds the maximum of %d task(s)", limit)
+		case depth+ahead >= limit:
+			return fmt.Errorf("Queue is full: the tasks before this one reach the maximum of %d task(s)", limit)
+		}
+	}
+	return nil
+}
+
+// runsBefore reports whether a belongs ahead of b in the queue: higher
+// priorities first, then, with Config.ShortestFirst, less time remaining.
+func (t *Timer) runsBefore(a, b Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return t.Config.ShortestFirst && a.Remaining < b.Remaining
+}
+
+// Remove deletes and returns the pending task at index i.
+func (t *Timer) Remove(i int) (Task, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.checkIndex(i); err != nil {
+		return Task{}, err
+	}
+	task := t.queue[i]
+	t.queue = append(t.queue[:i], t.queue[i+1:]...)
+	t.persist()
+	return task, nil
+}
+
+// Insert puts task into the queue at index i, or at the end if the queue
+// is shorter than that, and returns the index used.
+func (t *Timer) Insert(i int, task Task) int {
+	if task.Remaining <= 0 {
+		task.Remaining = task.Duration
+	}
+
+	t.mu.Lock()
+	i = min(max(i, 0), len(t.queue))
+	t.queue = slices.Insert(t.queue, i, task)
+	t.persist()
+	t.mu.Unlock()
+
+	t.notify()
+	return i
+}
+
+// Withdraw removes the pending task queued last that is the same as task.
+// It fails if that task has already started.
+func (t *Timer) Withdraw(task Task) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := len(t.queue) - 1; i >= 0; i-- {
+		if sameTask(t.queue[i], task) {
+			t.queue = slices.Delete(t.queue, i, i+1)
+			t.persist()
+			return nil
+		}
+	}
+	return fmt.Errorf("task %s is no longer queued", task.Name)
+}
+
+// sameTask compares tasks as they were added, ignoring progress.
+func sameTask(a, b Task) bool {
+	return a.Name == b.Name && a.Duration == b.Duration && a.Priority == b.Priority &&
+		a.RepeatCount == b.RepeatCount && a.RepeatForever == b.RepeatForever &&
+		a.StartAt.Equal(b.StartAt) && slices.Equal(a.Tags, b.Tags)
+}
+
+// Rename changes the name of the pending task at index i.
+func (t *Timer) Rename(i int, name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.checkIndex(i); err != nil {
+		return err
+	}
+	t.queue[i].Name = name
+	t.persist()
+	return nil
+}
+
+// RenameCurrent changes the name of the longest running task. The new
+// name is the one recorded in history.
+func (t *Timer) RenameCurrent(name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return ErrNotRunning
+	}
+	t.active[0].task.Name = name
+	return nil
+}
+
+// Duplicate appends a copy of the pending task at index i to the end of
+// the queue and returns the new queue length.
+func (t *Timer) Duplicate(i int) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.checkIndex(i); err != nil {
+		return 0, err
+	}
+	return t.appendCopy(t.queue[i]), nil
+}
+
+// DuplicateCurrent appends a fresh copy of the longest running task to
+// the end of the queue and returns the new queue length.
+func (t *Timer) DuplicateCurrent() (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return 0, ErrNotRunning
+	}
+	task := *t.active[0].task
+	task.Remaining = task.Duration
+	task.Logged = 0
+	task.StartAt = time.Time{}
+	return t.appendCopy(task), nil
+}
+
+// appendCopy adds a copy of task to the end of the queue. The caller must
+// hold t.mu.
+func (t *Timer) appendCopy(task Task) int {
+	task.Tags = slices.Clone(task.Tags)
+	t.queue = append(t.queue, task)
+	t.persist()
+	t.notify()
+	return len(t.queue)
+}
+
+// Swap exchanges the pending tasks at indexes i and j.
+func (t *Timer) Swap(i, j int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.checkIndex(i); err != nil {
+		return err
+	}
+	if err := t.checkIndex(j); err != nil {
+		return err
+	}
+	t.queue[i], t.queue[j] = t.queue[j], t.queue[i]
+	t.persist()
+	return nil
+}
+
+// Move takes the pending task at index i out of the queue and reinserts
+// it so that it ends up at index j, shifting the tasks in between.
+func (t *Timer) Move(i, j int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.checkIndex(i); err != nil {
+		return err
+	}
+	if err := t.checkIndex(j); err != nil {
+		return err
+	}
+	task := t.queue[i]
+	t.queue = slices.Insert(slices.Delete(t.queue, i, i+1), j, task)
+	t.persist()
+	return nil
+}
+
+// Clear discards every pending task and returns how many there were. The
+// running task, which is no longer part of the queue, keeps going.
+func (t *Timer) Clear() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := len(t.queue)
+	t.queue = nil
+	t.persist()
+	return n
+}
+
+// Queue returns a copy of the pending tasks.
+func (t *Timer) Queue() []Task {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Task(nil), t.queue...)
+}
+
+// AddNote appends a line of notes to the longest running task.
+func (t *Timer) AddNote(note string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return ErrNotRunning
+	}
+	task := t.active[0].task
+	if task.Notes != "" {
+		task.Notes += "\n"
+	}
+	task.Notes += note
+	return nil
+}
+
+// Current returns a copy of the longest running task, if any.
+func (t *Timer) Current() (Task, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return Task{}, false
+	}
+	return *t.active[0].task, true
+}
+
+// Running returns copies of every running task, oldest first.
+func (t *Timer) Running() []Task {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tasks := make([]Task, len(t.active))
+	for i, active := range t.active {
+		tasks[i] = *active.task
+	}
+	return tasks
+}
+
+// IdleFor reports how long the timer has had nothing running and nothing
+// queued, or zero if it is busy.
+func (t *Timer) IdleFor() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) > 0 || len(t.queue) > 0 {
+		return 0
+	}
+	return time.Since(t.lastBusy)
+}
+
+// Paused reports whether there are running tasks and all of them are
+// paused.
+func (t *Timer) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, active := range t.active {
+		if !active.paused {
+			return false
+		}
+	}
+	return len(t.active) > 0
+}
+
+// Pause freezes the countdown of every running task.
+func (t *Timer) Pause() error {
+	return t.setPaused(true, ErrAlreadyPaused)
+}
+
+// Resume continues paused countdowns from where they stopped.
+func (t *Timer) Resume() error {
+	return t.setPaused(false, ErrNotPaused)
+}
+
+// setPaused moves every running task into the paused state given,
+// returning unchanged if none of them needed to move.
+func (t *Timer) setPaused(paused bool, unchanged error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return ErrNotRunning
+	}
+
+	changed := false
+	for _, active := range t.active {
+		if active.paused == paused {
+			continue
+		}
+		active.paused = paused
+		poke(active.pauseCh)
+		changed = true
+	}
+	if !changed {
+		return unchanged
+	}
+	return nil
+}
+
+// Extend adds d to the countdown of every running task.
+func (t *Timer) Extend(d time.Duration) error {
+	return t.adjust(d)
+}
+
+// Shorten subtracts d from the countdown of every running task. A task
+// left with no time remaining completes immediately.
+func (t *Timer) Shorten(d time.Duration) error {
+	return t.adjust(-d)
+}
+
+// adjust moves the end time of every running task by delta.
+func (t *Timer) adjust(delta time.Duration) error {
+	if t.Config.CountUp {
+		return errors.New("there is no end time to adjust when counting up")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return ErrNotRunning
+	}
+	for _, active := range t.active {
+		select {
+		case active.extendCh <- delta:
+		default:
+			return errors.New("too many pending time adjustments")
+		}
+	}
+	return nil
+}
+
+// Done completes the running tasks when counting up, recording the time
+// spent on them.
+func (t *Timer) Done() error {
+	if !t.Config.CountUp {
+		return ErrNotCountingUp
+	}
+	return t.abort(errDone)
+}
+
+// Cancel stops the running tasks without recording them in history.
+func (t *Timer) Cancel() error {
+	return t.abort(context.Canceled)
+}
+
+// Skip stops the running tasks, recording them in history as skipped
+// along with the time spent on them.
+func (t *Timer) Skip() error {
+	return t.abort(errSkipped)
+}
+
+// abort cancels every running task with cause, which run inspects to
+// decide what to record.
+func (t *Timer) abort(cause error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.active) == 0 {
+		return ErrNotRunning
+	}
+	for _, active := range t.active {
+		active.cancel(cause)
+	}
+	return nil
+}
+
+// History returns the entries recorded in the history file.
+func (t *Timer) History() ([]HistoryEntry, error) {
+	return readHistory(t.Config.HistoryFile)
+}
+
+// ArchiveHistory moves the history file and its rotated backups aside and
+// starts an empty history, returning the path of the archived file.
+func (t *Timer) ArchiveHistory() (string, error) {
+	t.historyMu.Lock()
+	defer t.historyMu.Unlock()
+	return archiveHistory(t.Config.HistoryFile, time.Now())
+}
+
+// checkIndex reports an error unless i indexes the queue. Positions in the
+// message are 1-based, as users see them. The caller must hold t.mu.
+func (t *Timer) checkIndex(i int) error {
+	if i < 0 || i >= len(t.queue) {
+		return fmt.Errorf("task number %d out of range (queue has %d tasks)", i+1, len(t.queue))
+	}
+	return nil
+}
+
+// notify wakes Start if it is waiting for tasks.
+func (t *Timer) notify() {
+	poke(t.wake)
+}
+
+// isPaused reads the pause state that setPaused last requested.
+func (t *Timer) isPaused(active *activeTask) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return active.paused
+}
+
+// poke never blocks: receivers re-read the state they are told about,
+// so a signal already pending covers this one.
+func poke(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
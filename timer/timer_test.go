@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "hours", input: "-h 2", want: 2 * time.Hour},
+		{name: "minutes", input: "-m 25", want: 25 * time.Minute},
+		{name: "seconds", input: "-s 45", want: 45 * time.Second},
+		{name: "all flags", input: "-h 1 -m 30 -s 15", want: time.Hour + 30*time.Minute + 15*time.Second},
+		{name: "flags in any order", input: "-s 5 -h 1", want: time.Hour + 5*time.Second},
+		{name: "double dash flags", input: "--m 10", want: 10 * time.Minute},
+		{name: "zero values", input: "-h 0 -m 0 -s 0", want: 0},
+		{name: "surrounding spaces", input: "  -m 1  ", want: time.Minute},
+		{name: "negative hours", input: "-h -1", wantErr: true},
+		{name: "negative minutes", input: "-m -5", wantErr: true},
+		{name: "negative seconds", input: "-s -10", wantErr: true},
+		{name: "unknown flag", input: "-d 3", wantErr: true},
+		{name: "non integer", input: "-m abc", wantErr: true},
+		{name: "missing value", input: "-m", wantErr: true},
+		{name: "trailing argument", input: "-m 5 soon", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "blank", input: "   ", wantErr: true},
+
+		{name: "iso hours minutes seconds", input: "PT1H30M45S", want: time.Hour + 30*time.Minute + 45*time.Second},
+		{name: "iso days", input: "P1DT2H", want: 26 * time.Hour},
+		{name: "iso lower case", input: "pt15m", want: 15 * time.Minute},
+		{name: "iso bare P", input: "P", wantErr: true},
+		{name: "iso trailing T", input: "P1DT", wantErr: true},
+		{name: "iso empty time part", input: "PT", wantErr: true},
+		{name: "iso bad unit", input: "PT5X", wantErr: true},
+		{name: "iso time without value", input: "PTM", wantErr: true},
+		{name: "iso overflow", input: "PT99999999999999999999H", wantErr: true},
+
+		{name: "compact seconds", input: "90s", want: 90 * time.Second},
+		{name: "compact minutes", input: "25m", want: 25 * time.Minute},
+		{name: "compact hours", input: "1h", want: time.Hour},
+		{name: "compact hours minutes", input: "1h30m", want: 90 * time.Minute},
+		{name: "compact full", input: "2h15m30s", want: 2*time.Hour + 15*time.Minute + 30*time.Second},
+		{name: "compact upper case", input: "1H30M", want: 90 * time.Minute},
+		{name: "compact with spaces", input: "1h 30m", want: 90 * time.Minute},
+		{name: "compact missing unit", input: "25", wantErr: true},
+		{name: "compact bad unit", input: "5x", wantErr: true},
+		{name: "compact overflow", input: "99999999999999999999 m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDuration(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDuration(%q) = %v, want error", tt.input, got)
+				}
+				var parseErr *DurationParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("parseDuration(%q) error %v is not a *DurationParseError", tt.input, err)
+				}
+				if parseErr.Input != strings.TrimSpace(tt.input) {
+					t.Errorf("DurationParseError.Input = %q, want %q", parseErr.Input, strings.TrimSpace(tt.input))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDuration(%q) returned error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	// Words that only look like the start of a duration stay in the task
+	// name: "add PT session 30m" names the task "PT session".
+	for input, want := range map[string]bool{"P": false, "PT": false, "PT30M": true, "30m": true, "session": false} {
+		if got := isDurationToken(input); got != want {
+			t.Errorf("isDurationToken(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestAfterEachCommandQuotesTask(t *testing.T) {
+	t.Chdir(t.TempDir())
+	old := afterEach
+	afterEach = `printf '%s|%s' {task} "{duration}"`
+	defer func() { afterEach = old }()
+
+	name := `$(touch pwned) it's done`
+	out, err := afterEachCommand(Task{Name: name, Duration: 90 * time.Second}).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out), name+"|1m30s"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if _, err := os.Stat("pwned"); err == nil {
+		t.Error("task name was run as a command")
+	}
+}
+
+func TestInterruptedTaskIsNotCountedTwice(t *testing.T) {
+	dir := t.TempDir()
+	config := Config{
+		HistoryFile: filepath.Join(dir, "history.log"),
+		StateFile:   filepath.Join(dir, "state.json"),
+		Output:      io.Discard,
+	}
+
+	first := NewTimer(config)
+	first.Add(Task{Name: "Write", Duration: 3 * time.Second, Remaining: 3 * time.Second})
+	stopped := make(chan error)
+	go func() { stopped <- first.Start() }()
+	time.Sleep(1500 * time.Millisecond)
+	first.Interrupt()
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, err := loadState(config.StateFile)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("loadState = %v, %v; want the interrupted task", tasks, err)
+	}
+	second := NewTimer(config)
+	second.OnComplete = func(Task) { second.Stop() }
+	second.Insert(0, tasks[0])
+	if err := second.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := second.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total time.Duration
+	var statuses []string
+	for _, e := range entries {
+		total += e.Duration
+		statuses = append(statuses, e.Status)
+	}
+	if want := []string{StatusInterrupted, StatusCompleted}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if total < 2*time.Second || total > 4*time.Second {
+		t.Errorf("history adds up to %s, want about 3s", total)
+	}
+}
+
+func TestClearKeepsRunningTask(t *testing.T) {
+	timer := NewTimer(Config{HistoryFile: filepath.Join(t.TempDir(), "history.log"), Output: io.Discard})
+	for _, name := range []string{"Running", "Pending 1", "Pending 2"} {
+		timer.Add(Task{Name: name, Duration: time.Minute, Remaining: time.Minute})
+	}
+	go timer.Start()
+	defer timer.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(timer.Running()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no task started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := timer.Clear(); n != 2 {
+		t.Errorf("Clear() = %d, want 2", n)
+	}
+	if queue := timer.Queue(); len(queue) != 0 {
+		t.Errorf("queue after Clear = %v, want empty", queue)
+	}
+	if current, ok := timer.Current(); !ok || current.Name != "Running" {
+		t.Errorf("running task after Clear = %q, %v; want Running", current.Name, ok)
+	}
+}
+
+func TestRotateWithoutBackupsKeepsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.log")
+	if err := os.WriteFile(path, []byte("Task|25m|2024-01-01 09:00:00|completed|\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rotateIfNeeded(path, 10, 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(backupName(path, 1))
+	if err != nil {
+		t.Fatalf("history was not kept as a backup: %v", err)
+	}
+	if !strings.Contains(string(data), "Task|25m|") {
+		t.Errorf("backup holds %q, want the old history", data)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the subset of TOML task files need: key/value pairs,
+// [tables] and [[arrays of tables]] with dotted names, and values that
+// are strings, numbers, booleans, arrays (which may span lines) and
+// inline tables. Dates are read as plain strings.
+func parseTOML(r io.Reader) (*docNode, error) {
+	root := newDocMap(1)
+	table := root
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[["):
+			if !strings.HasSuffix(line, "]]") {
+				return nil, &lineError{n, "unterminated [[table]] header"}
+			}
+			parent, key, err := tomlTablePath(root, line[2:len(line)-2], n)
+			if err != nil {
+				return nil, err
+			}
+			list, ok := parent.Values[key]
+			if !ok {
+				list = &docNode{Kind: docList, Line: n}
+				parent.set(key, list)
+			} else if list.Kind != docList {
+				return nil, &lineError{n, fmt.Sprintf("%q is already defined as a value", key)}
+			}
+			table = newDocMap(n)
+			list.Items = append(list.Items, table)
+			continue
+
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, &lineError{n, "unterminated [table] header"}
+			}
+			parent, key, err := tomlTablePath(root, line[1:len(line)-1], n)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := parent.Values[key]; dup {
+				return nil, &lineError{n, fmt.Sprintf("table %q is defined twice", key)}
+			}
+			table = newDocMap(n)
+			parent.set(key, table)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, &lineError{n, fmt.Sprintf("expected \"key = value\", got %q", line)}
+		}
+		key = unquoteTOMLKey(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		// An array may go on over several lines until its brackets close.
+		start := n
+		for strings.HasPrefix(value, "[") && !tomlBalanced(value) && scanner.Scan() {
+			n++
+			value += " " + strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		}
+
+		if key == "" {
+			return nil, &lineError{start, "empty key"}
+		}
+		if _, dup := table.Values[key]; dup {
+			return nil, &lineError{start, fmt.Sprintf("duplicate key %q", key)}
+		}
+		node, err := parseTOMLValue(value, start)
+		if err != nil {
+			return nil, err
+		}
+		table.set(key, node)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func newDocMap(line int) *docNode {
+	return &docNode{Kind: docMap, Line: line, Values: map[string]*docNode{}}
+}
+
+// set adds key to a mapping.
+func (node *docNode) set(key string, value *docNode) {
+	node.Keys = append(node.Keys, key)
+	node.Values[key] = value
+}
+
+// tomlTablePath finds, creating tables on the way, the table holding
+// the last part of a dotted header name, and returns it with that part.
+// A part naming an array of tables means its latest table.
+func tomlTablePath(root *docNode, name string, n int) (*docNode, string, error) {
+	parts := strings.Split(name, ".")
+	table := root
+	for i, part := range parts {
+		parts[i] = unquoteTOMLKey(strings.TrimSpace(part))
+		if parts[i] == "" {
+			return nil, "", &lineError{n, fmt.Sprintf("invalid table name %q", name)}
+		}
+	}
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table.Values[part]
+		switch {
+		case !ok:
+			next = newDocMap(n)
+			table.set(part, next)
+		case next.Kind == docList && len(next.Items) > 0:
+			next = next.Items[len(next.Items)-1]
+		}
+		if next.Kind != docMap {
+			return nil, "", &lineError{n, fmt.Sprintf("%q is not a table", part)}
+		}
+		table = next
+	}
+	return table, parts[len(parts)-1], nil
+}
+
+// stripTOMLComment removes a # comment outside quotes.
+func stripTOMLComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlBalanced reports whether the brackets outside quotes in s close.
+func tomlBalanced(s string) bool {
+	parts, err := splitTOMLValues(s, 0)
+	return err == nil && len(parts) == 1
+}
+
+func unquoteTOMLKey(key string) string {
+	if unquoted, err := unquoteTOMLString(key); err == nil {
+		return unquoted
+	}
+	return key
+}
+
+func unquoteTOMLString(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1], nil
+	}
+	return "", fmt.Errorf("%s is not a string", s)
+}
+
+// parseTOMLValue parses the value of a key on line n.
+func parseTOMLValue(s string, n int) (*docNode, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return nil, &lineError{n, "missing value"}
+
+	case s[0] == '"' || s[0] == '\'':
+		value, err := unquoteTOMLString(s)
+		if err != nil {
+			return nil, &lineError{n, fmt.Sprintf("invalid string %s", s)}
+		}
+		return &docNode{Kind: docScalar, Line: n, Value: value}, nil
+
+	case s[0] == '[':
+		if !strings.HasSuffix(s, "]") {
+			return nil, &lineError{n, "unterminated array"}
+		}
+		parts, err := splitTOMLValues(s[1:len(s)-1], n)
+		if err != nil {
+			return nil, err
+		}
+		node := &docNode{Kind: docList, Line: n}
+		for _, part := range parts {
+			if strings.TrimSpace(part) == "" {
+				// A trailing comma is allowed.
+				continue
+			}
+			item, err := parseTOMLValue(part, n)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, item)
+		}
+		return node, nil
+
+	case s[0] == '{':
+		if !strings.HasSuffix(s, "}") {
+			return nil, &lineError{n, "unterminated inline table"}
+		}
+		parts, err := splitTOMLValues(s[1:len(s)-1], n)
+		if err != nil {
+			return nil, err
+		}
+		node := newDocMap(n)
+		for _, part := range parts {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, &lineError{n, fmt.Sprintf("expected \"key = value\", got %q", strings.TrimSpace(part))}
+			}
+			key = unquoteTOMLKey(strings.TrimSpace(key))
+			if _, dup := node.Values[key]; dup {
+				return nil, &lineError{n, fmt.Sprintf("duplicate key %q", key)}
+			}
+			child, err := parseTOMLValue(value, n)
+			if err != nil {
+				return nil, err
+			}
+			node.set(key, child)
+		}
+		return node, nil
+	}
+
+	// Anything else must be a bare number, boolean or date.
+	value := strings.ReplaceAll(s, "_", "")
+	_, numErr := strconv.ParseFloat(value, 64)
+	isDate := s[0] >= '0' && s[0] <= '9' && strings.ContainsAny(s, "-:")
+	if numErr != nil && !isDate && s != "true" && s != "false" {
+		return nil, &lineError{n, fmt.Sprintf("invalid value %s; strings must be quoted", s)}
+	}
+	return &docNode{Kind: docScalar, Line: n, Value: value}, nil
+}
+
+// splitTOMLValues splits the inside of an array or inline table at the
+// commas that are not nested or quoted.
+func splitTOMLValues(s string, n int) ([]string, error) {
+	var parts []string
+	var quote rune
+	escaped := false
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, &lineError{n, "unbalanced brackets or quotes"}
+	}
+	return append(parts, s[start:]), nil
+}
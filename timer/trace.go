@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statusCancelled is the span status of a task that was stopped early.
+// Such tasks never reach history, so it has no history status.
+const statusCancelled = "cancelled"
+
+// Tracer sends spans to an OpenTelemetry collector over OTLP/HTTP with
+// JSON bodies, from a background goroutine like historySync. A nil
+// Tracer records nothing.
+type Tracer struct {
+	url     string
+	service string
+	client  *http.Client
+	done    chan struct{}
+
+	// mu guards pending against end racing close.
+	mu      sync.Mutex
+	closed  bool
+	pending chan *Span
+}
+
+// Span is one timed operation of a trace.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID []byte
+	name     string
+	start    time.Time
+	attrs    []otlpAttribute
+	err      error
+	end      time.Time
+}
+
+// newTracerFromEnv returns a Tracer for the collector named by the
+// standard OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT
+// variables, or nil if neither is set.
+func newTracerFromEnv() *Tracer {
+	url := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if url == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return nil
+		}
+		url = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	if service == "" {
+		service = "timer"
+	}
+
+	tr := &Tracer{
+		url:     url,
+		service: service,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: make(chan *Span, 100),
+		done:    make(chan struct{}),
+	}
+	go tr.run()
+	return tr
+}
+
+// Start begins a span, in a new trace unless parent is given.
+func (tr *Tracer) Start(name string, parent *Span) *Span {
+	if tr == nil {
+		return nil
+	}
+	s := &Span{tracer: tr, name: name, start: time.Now()}
+	rand.Read(s.spanID[:])
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID[:]
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	return s
+}
+
+// SetString records a string attribute.
+func (s *Span) SetString(key, value string) {
+	if s != nil {
+		s.attrs = append(s.attrs, otlpAttribute{Key: key, Value: otlpValue{String: &value}})
+	}
+}
+
+// SetInt records an integer attribute.
+func (s *Span) SetInt(key string, value int64) {
+	if s != nil {
+		v := strconv.FormatInt(value, 10)
+		s.attrs = append(s.attrs, otlpAttribute{Key: key, Value: otlpValue{Int: &v}})
+	}
+}
+
+// SetError marks the span as failed if err is not nil.
+func (s *Span) SetError(err error) {
+	if s != nil && err != nil {
+		s.err = err
+	}
+}
+
+// End finishes the span and queues it for export. If the queue is full
+// the span is dropped and a warning logged.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+
+	tr := s.tracer
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.closed {
+		return
+	}
+	select {
+	case tr.pending <- s:
+	default:
+		slog.Warn("trace export queue full, dropping span", "span", s.name)
+	}
+}
+
+// close waits a little for the queued spans to be sent.
+func (tr *Tracer) close() {
+	if tr == nil {
+		return
+	}
+	tr.mu.Lock()
+	tr.closed = true
+	close(tr.pending)
+	tr.mu.Unlock()
+
+	select {
+	case <-tr.done:
+	case <-time.After(syncFlushTimeout):
+		slog.Warn("gave up waiting for trace export to finish", "url", tr.url)
+	}
+}
+
+func (tr *Tracer) run() {
+	defer close(tr.done)
+	slog.Debug("trace exporter goroutine started")
+	for s := range tr.pending {
+		backoff := syncBackoff
+		err := tr.export(s)
+		for retry := 1; err != nil && retry <= syncRetries; retry++ {
+			time.Sleep(backoff)
+			backoff *= 2
+			err = tr.export(s)
+		}
+		if err != nil {
+			slog.Warn("cannot export span", "span", s.name, "url", tr.url, "err", err)
+		}
+	}
+}
+
+// The OTLP JSON encoding of a trace export request, as far as it is used.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpSpan struct {
+		TraceID      string          `json:"traceId"`
+		SpanID       string          `json:"spanId"`
+		ParentSpanID string          `json:"parentSpanId,omitempty"`
+		Name         string          `json:"name"`
+		Kind         int             `json:"kind"`
+		Start        string          `json:"startTimeUnixNano"`
+		End          string          `json:"endTimeUnixNano"`
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		Status       otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		String *string `json:"stringValue,omitempty"`
+		// Int is a decimal string, as OTLP JSON encodes 64-bit integers.
+		Int *string `json:"intValue,omitempty"`
+	}
+	otlpStatus struct {
+		// Code is 1 for OK and 2 for an error.
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (tr *Tracer) export(s *Span) error {
+	span := otlpSpan{
+		TraceID:      hex.EncodeToString(s.traceID[:]),
+		SpanID:       hex.EncodeToString(s.spanID[:]),
+		ParentSpanID: hex.EncodeToString(s.parentID),
+		Name:         s.name,
+		Kind:         1, // internal
+		Start:        strconv.FormatInt(s.start.UnixNano(), 10),
+		End:          strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:   s.attrs,
+		Status:       otlpStatus{Code: 1},
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+
+	scope := otlpScopeSpans{Spans: []otlpSpan{span}}
+	scope.Scope.Name = "timer"
+	service := tr.service
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpValue{String: &service}},
+		}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	resp, err := tr.client.Post(tr.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector replied %s", resp.Status)
+	}
+	return nil
+}
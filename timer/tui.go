@@ -0,0 +1,576 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// tuiRefresh is how often the full-screen interface is redrawn
+	// when nothing else prompts it.
+	tuiRefresh = 250 * time.Millisecond
+	// tuiLogLines is how much output the log panel keeps.
+	tuiLogLines = 200
+)
+
+// bigDigits is the five-row font of the large countdown.
+var bigDigits = map[rune][5]string{
+	'0': {"███", "█ █", "█ █", "█ █", "███"},
+	'1': {" █ ", "██ ", " █ ", " █ ", "███"},
+	'2': {"███", "  █", "███", "█  ", "███"},
+	'3': {"███", "  █", "███", "  █", "███"},
+	'4': {"█ █", "█ █", "███", "  █", "  █"},
+	'5': {"███", "█  ", "███", "  █", "███"},
+	'6': {"███", "█  ", "███", "█ █", "███"},
+	'7': {"███", "  █", "  █", "  █", "  █"},
+	'8': {"███", "█ █", "███", "█ █", "███"},
+	'9': {"███", "█ █", "███", "  █", "███"},
+	':': {" ", "█", " ", "█", " "},
+}
+
+// tuiMode is what the keyboard is doing in the full-screen interface.
+type tuiMode int
+
+const (
+	// modeKeys runs a shortcut for each key.
+	modeKeys tuiMode = iota
+	// modeCommand edits a command line, opened with ':'.
+	modeCommand
+	// modeAdd edits the name and duration of a task to add, opened with 'a'.
+	modeAdd
+	// modeQuit asks whether to quit, opened with 'q'.
+	modeQuit
+)
+
+// tuiLegend lists the shortcuts of modeKeys.
+const tuiLegend = "space pause  n skip  a add  ↑↓ select  enter first  d delete  : command  q quit  ? hide"
+
+// tui is the full-screen interface: a header with a large countdown of
+// the running task, the queue, a log of everything the commands print
+// and a line for typing. It reads the keyboard itself, sending the
+// commands keys stand for to the command loop, and captures stdout and
+// stderr for the log.
+type tui struct {
+	timer *Timer
+	cmdCh chan<- string
+	// interrupt is called for Ctrl-C, which the terminal no longer turns
+	// into SIGINT.
+	interrupt func()
+	// term is the terminal, which stdout no longer points at.
+	term           *os.File
+	stdout, stderr *os.File
+	pipe           *os.File
+	restore        func()
+
+	mu      sync.Mutex
+	log     []string
+	partial string
+	input   []rune
+	mode    tuiMode
+	// selected is the queue entry highlighted for 'd' and Enter, counted
+	// from 0.
+	selected int
+	// rowTasks maps the screen rows of the queue panel, counted from 1,
+	// to the queue entries drawn there, for mouse clicks.
+	rowTasks map[int]int
+	legend   bool
+	bell     bool
+	width    int
+	height   int
+
+	dirty   chan struct{}
+	resized chan os.Signal
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// startTUI takes over the terminal until stop is called. Commands typed
+// are sent to cmdCh and Ctrl-C calls interrupt.
+func startTUI(t *Timer, cmdCh chan<- string, interrupt func()) (*tui, error) {
+	if _, _, err := terminalSize(os.Stdout); err != nil {
+		return nil, err
+	}
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		restore()
+		return nil, err
+	}
+
+	ui := &tui{
+		timer:     t,
+		cmdCh:     cmdCh,
+		interrupt: interrupt,
+		term:      os.Stdout,
+		stdout:    os.Stdout,
+		stderr:    os.Stderr,
+		pipe:      w,
+		restore:   restore,
+		legend:    true,
+		dirty:     make(chan struct{}, 1),
+		resized:   make(chan os.Signal, 1),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	os.Stdout, os.Stderr = w, w
+	if t.Config.Output == nil {
+		// The countdown is drawn by the interface instead.
+		t.Config.Output = io.Discard
+	}
+	// Use the alternate screen, hide the cursor and report mouse clicks
+	// in SGR encoding.
+	fmt.Fprint(ui.term, "\033[?1049h\033[?25l\033[?1000h\033[?1006h")
+	notifyResize(ui.resized)
+
+	go ui.capture(r)
+	go ui.readKeys()
+	go ui.loop()
+	return ui, nil
+}
+
+// stop gives the terminal back as it was.
+func (ui *tui) stop() {
+	close(ui.done)
+	<-ui.stopped
+	fmt.Fprint(ui.term, "\033[?1006l\033[?1000l\033[?25h\033[?1049l")
+	ui.restore()
+	os.Stdout, os.Stderr = ui.stdout, ui.stderr
+	ui.pipe.Close()
+}
+
+func (ui *tui) redraw() {
+	poke(ui.dirty)
+}
+
+func (ui *tui) loop() {
+	defer close(ui.stopped)
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+
+	for {
+		ui.draw()
+		select {
+		case <-ticker.C:
+		case <-ui.dirty:
+		case <-ui.resized:
+		case <-ui.done:
+			return
+		}
+	}
+}
+
+// capture adds what is written to stdout and stderr to the log. Only the
+// last of several carriage-return redraws of a line is kept.
+func (ui *tui) capture(r *os.File) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			ui.addOutput(string(buf[:n]))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (ui *tui) addOutput(text string) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	if strings.Contains(text, "\a") {
+		ui.bell = true
+		text = strings.ReplaceAll(text, "\a", "")
+	}
+	lines := strings.Split(ui.partial+ansiEscapeRe.ReplaceAllString(text, ""), "\n")
+	ui.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if line = lastRedraw(line); line != "" {
+			ui.addLog(line)
+		}
+	}
+	ui.redraw()
+}
+
+// addLog appends a line to the log panel. The caller must hold ui.mu.
+func (ui *tui) addLog(line string) {
+	ui.log = append(ui.log, strings.ReplaceAll(line, "\t", "    "))
+	if len(ui.log) > tuiLogLines {
+		ui.log = ui.log[len(ui.log)-tuiLogLines:]
+	}
+}
+
+// lastRedraw returns what a line ends up showing after any carriage
+// returns in it.
+func lastRedraw(line string) string {
+	if i := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	return strings.TrimRight(line, " \r")
+}
+
+// readKeys turns keys into commands until the terminal closes.
+func (ui *tui) readKeys() {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			ui.cmdCh <- "exit"
+			return
+		}
+		// Asked before taking ui.mu, which output written while holding
+		// the timer's lock could be waiting on.
+		paused := ui.timer.Paused()
+		for _, cmd := range ui.handleKeys(buf[:n], paused) {
+			ui.cmdCh <- cmd
+		}
+		ui.redraw()
+	}
+}
+
+// handleKeys applies keys and mouse clicks to the interface and returns
+// the commands they stand for. Ctrl-C interrupts whatever the mode.
+func (ui *tui) handleKeys(keys []byte, paused bool) []string {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	var cmds []string
+	for len(keys) > 0 {
+		r, size := utf8.DecodeRune(keys)
+		keys = keys[size:]
+
+		// Escape sequences are arrow keys and the like; a lone escape
+		// closes a prompt.
+		var seq string
+		if r == 0x1b && len(keys) > 0 && keys[0] == '[' {
+			i := 1
+			for i < len(keys) && (keys[i] < 0x40 || keys[i] > 0x7e) {
+				i++
+			}
+			seq = string(keys[:min(i+1, len(keys))])
+			keys = keys[len(seq):]
+		}
+
+		switch {
+		case r == 0x03:
+			ui.interrupt()
+		case r == 0x1b && seq == "" && ui.mode != modeKeys:
+			ui.mode, ui.input = modeKeys, nil
+		case ui.mode == modeQuit:
+			if r == 'y' || r == 'Y' {
+				cmds = append(cmds, "exit")
+			}
+			ui.mode = modeKeys
+		case ui.mode != modeKeys:
+			if cmd, ok := ui.editInput(r); ok {
+				cmds = append(cmds, cmd)
+			}
+		case strings.HasSuffix(ui.partial, "[y/N] "):
+			// A command is waiting for an answer to its question.
+			answer := "n"
+			if r == 'y' || r == 'Y' {
+				answer = "y"
+			}
+			ui.addLog(ui.partial + answer)
+			ui.partial = ""
+			cmds = append(cmds, answer)
+		case strings.HasPrefix(seq, "[<"):
+			cmds = append(cmds, ui.click(seq)...)
+		case seq == "[A" || r == 'k':
+			ui.selected = max(ui.selected-1, 0)
+		case seq == "[B" || r == 'j':
+			ui.selected++
+		case r == ' ':
+			if paused {
+				cmds = append(cmds, "resume")
+			} else {
+				cmds = append(cmds, "pause")
+			}
+		case r == 'n':
+			cmds = append(cmds, "skip")
+		case r == 'd':
+			cmds = append(cmds, fmt.Sprintf("remove %d", ui.selected+1))
+		case r == '\r' || r == '\n':
+			cmds = append(cmds, ui.moveToFront(ui.selected))
+		case r == 'a':
+			ui.mode = modeAdd
+		case r == ':':
+			ui.mode = modeCommand
+		case r == 'q':
+			ui.mode = modeQuit
+		case r == '?':
+			ui.legend = !ui.legend
+		}
+	}
+	return cmds
+}
+
+// click handles an SGR mouse report such as "[<0;12;7M", a left press
+// in column 12 of row 7. Clicking a queue entry highlights it and
+// clicking it again moves it to the front; a right click deletes it. The
+// wheel moves the highlight. The caller must hold ui.mu.
+func (ui *tui) click(seq string) []string {
+	var button, col, row int
+	var action byte
+	if _, err := fmt.Sscanf(seq, "[<%d;%d;%d%c", &button, &col, &row, &action); err != nil || action != 'M' {
+		return nil
+	}
+	switch button {
+	case 64:
+		ui.selected = max(ui.selected-1, 0)
+		return nil
+	case 65:
+		ui.selected++
+		return nil
+	}
+
+	i, ok := ui.rowTasks[row]
+	if !ok {
+		return nil
+	}
+	switch button {
+	case 0:
+		if i == ui.selected {
+			return []string{ui.moveToFront(i)}
+		}
+		ui.selected = i
+	case 2:
+		ui.selected = i
+		return []string{fmt.Sprintf("remove %d", i+1)}
+	}
+	return nil
+}
+
+// moveToFront returns the command making queue entry i the next to run,
+// and keeps it highlighted there. The caller must hold ui.mu.
+func (ui *tui) moveToFront(i int) string {
+	ui.selected = 0
+	return fmt.Sprintf("move %d 1", i+1)
+}
+
+// editInput applies r to the line being typed and, once Enter finishes
+// it, returns the command it makes. The caller must hold ui.mu.
+func (ui *tui) editInput(r rune) (string, bool) {
+	switch {
+	case r == '\r' || r == '\n':
+		line := strings.TrimSpace(string(ui.input))
+		mode := ui.mode
+		ui.mode, ui.input = modeKeys, nil
+		if line == "" {
+			return "", false
+		}
+		if mode == modeAdd {
+			line = "add " + line
+		}
+		ui.addLog("> " + line)
+		return line, true
+	case r == 0x7f || r == '\b':
+		if len(ui.input) > 0 {
+			ui.input = ui.input[:len(ui.input)-1]
+		}
+	case r == 0x04 && len(ui.input) == 0:
+		ui.mode = modeKeys
+	case r >= ' ' && r != utf8.RuneError:
+		ui.input = append(ui.input, r)
+	}
+	return "", false
+}
+
+// draw paints the whole screen in one write.
+func (ui *tui) draw() {
+	width, height, err := terminalSize(ui.term)
+	if err != nil || width < 10 || height < 6 {
+		return
+	}
+
+	running := ui.timer.Running()
+	queue := ui.timer.Queue()
+	paused := ui.timer.Paused()
+
+	ui.mu.Lock()
+	var b strings.Builder
+	if width != ui.width || height != ui.height {
+		b.WriteString("\033[2J")
+		ui.width, ui.height = width, height
+	}
+	if ui.bell {
+		b.WriteString("\a")
+		ui.bell = false
+	}
+	lines := ui.frame(width, height, running, queue, paused)
+	ui.mu.Unlock()
+
+	b.WriteString("\033[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line + "\033[K")
+	}
+	fmt.Fprint(ui.term, b.String())
+}
+
+// frame lays out the screen as height lines of at most width columns.
+// The caller must hold ui.mu but not the timer's lock, which output
+// written while holding it could otherwise wait on.
+func (ui *tui) frame(width, height int, running, queue []Task, paused bool) []string {
+	status := "idle"
+	switch {
+	case paused:
+		status = "paused"
+	case len(running) > 0:
+		status = "running"
+	}
+	title := fit(fmt.Sprintf(" timer  %s, %d queued", status, len(queue)), width)
+	lines := []string{"\033[7m" + title + strings.Repeat(" ", width-utf8.RuneCountInString(title)) + "\033[0m", ""}
+
+	// The header: the running task's countdown, large if there is room.
+	clock, name := "--:--", "No task running"
+	if len(running) > 0 {
+		task := running[0]
+		left := task.Remaining
+		if ui.timer.Config.CountUp {
+			left = task.Duration
+		}
+		clock, name = formatClock(left), task.Name
+		if paused {
+			name += " (paused)"
+		}
+	}
+	if big := bigClock(clock); height >= 16 && utf8.RuneCountInString(big[0]) <= width {
+		for _, row := range big {
+			lines = append(lines, center(row, width))
+		}
+	} else {
+		lines = append(lines, center(clock, width))
+	}
+	lines = append(lines, center(fit(name, width), width), "")
+
+	// The bottom lines are the legend, if shown, and the line for typing.
+	bottom := []string{ui.inputLine(width)}
+	if ui.legend && ui.mode == modeKeys {
+		bottom = append([]string{"\033[2m" + fit(tuiLegend, width) + "\033[0m"}, bottom...)
+	}
+
+	// The rest is shared by the queue and the log.
+	rest := height - len(lines) - len(bottom)
+	var items []string
+	for _, task := range running[min(1, len(running)):] {
+		items = append(items, fit(fmt.Sprintf("   [running] %s  %s left", task.Name, task.Remaining.Round(time.Second)), width))
+	}
+	ui.selected = max(min(ui.selected, len(queue)-1), 0)
+	selected := len(items) + ui.selected
+	var total time.Duration
+	for i, task := range queue {
+		total += task.Duration
+		item := fit(fmt.Sprintf("%3d. %s  %s  %s", i+1, task.Name, shortDuration(task.Duration.Round(time.Second)), priorityString(task.Priority)), width)
+		if i == ui.selected {
+			item = "\033[7m" + item + "\033[0m"
+		}
+		items = append(items, item)
+	}
+	queueRows := min(1+len(items), max(rest/2, 1))
+	lines = append(lines, fit(fmt.Sprintf("Queue: %d task(s), total %s", len(queue), shortDuration(total.Round(time.Second))), width))
+	// Scroll the queue to keep the highlighted task in view.
+	first := max(selected-(queueRows-2), 0)
+	ui.rowTasks = make(map[int]int)
+	for n, item := range items[min(first, len(items)):min(first+queueRows-1, len(items))] {
+		if i := first + n - (len(items) - len(queue)); i >= 0 {
+			ui.rowTasks[len(lines)+1] = i
+		}
+		lines = append(lines, item)
+	}
+
+	logRows := rest - queueRows
+	if logRows > 0 {
+		log := ui.log
+		if partial := lastRedraw(ui.partial); partial != "" {
+			log = append(log[:len(log):len(log)], partial)
+		}
+		lines = append(lines, fit("Log", width))
+		for _, line := range log[max(len(log)-(logRows-1), 0):] {
+			lines = append(lines, fit("  "+line, width))
+		}
+	}
+	for len(lines) < height-len(bottom) {
+		lines = append(lines, "")
+	}
+	return append(lines[:height-len(bottom)], bottom...)
+}
+
+// inputLine is the bottom line: what is being typed, the quit question,
+// or a hint when the keys are shortcuts. The caller must hold ui.mu.
+func (ui *tui) inputLine(width int) string {
+	var prompt string
+	switch ui.mode {
+	case modeKeys:
+		if strings.HasSuffix(ui.partial, "[y/N] ") {
+			return fit("Press y for yes, any other key for no", width)
+		}
+		if !ui.legend {
+			return fit("Press ? for keys", width)
+		}
+		return ""
+	case modeQuit:
+		return fit("Quit? [y/N]", width)
+	case modeAdd:
+		prompt = "Add task (name duration): "
+	case modeCommand:
+		prompt = ": "
+	}
+
+	input := string(ui.input)
+	if over := utf8.RuneCountInString(prompt+input) + 1 - width; over > 0 {
+		input = string([]rune(input)[min(over, len(ui.input)):])
+	}
+	return fit(prompt+input+"█", width)
+}
+
+// formatClock shows d as H:MM:SS, or MM:SS under an hour.
+func formatClock(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// bigClock renders clock in bigDigits, one string per row.
+func bigClock(clock string) [5]string {
+	var rows [5]string
+	for i, r := range clock {
+		glyph, ok := bigDigits[r]
+		if !ok {
+			glyph = [5]string{"   ", "   ", "───", "   ", "   "}
+		}
+		for row := range rows {
+			if i > 0 {
+				rows[row] += " "
+			}
+			rows[row] += glyph[row]
+		}
+	}
+	return rows
+}
+
+// fit cuts s to at most width runes.
+func fit(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:max(width-1, 0)]) + "…"
+}
+
+// center pads s on the left to put it in the middle of width columns.
+func center(s string, width int) string {
+	return strings.Repeat(" ", max((width-utf8.RuneCountInString(s))/2, 0)) + s
+}
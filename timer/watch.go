@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often --watch-file looks for new lines.
+const watchInterval = 500 * time.Millisecond
+
+// watchFile polls path and sends every line appended to it to cmdCh as an
+// add command, with or without the leading "add" as in a task file. Lines
+// already in the file are skipped; a truncated file is read again from
+// the start. The file need not exist yet.
+func watchFile(path string, cmdCh chan<- string) {
+	slog.Debug("watch goroutine started", "file", path)
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	var partial string
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Size() < offset {
+			offset, partial = 0, ""
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		data, err := readFrom(path, offset)
+		if err != nil {
+			slog.Warn("cannot read watched file", "file", path, "err", err)
+			continue
+		}
+		offset += int64(len(data))
+
+		// The last piece has no newline yet and waits for the rest.
+		lines := strings.Split(partial+string(data), "\n")
+		partial = lines[len(lines)-1]
+		for _, line := range lines[:len(lines)-1] {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if fields := strings.Fields(line); !strings.EqualFold(fields[0], "add") {
+				line = "add " + line
+			}
+			cmdCh <- line
+		}
+	}
+}
+
+// readFrom returns the contents of path after offset.
+func readFrom(path string, offset int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(file)
+}
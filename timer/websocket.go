@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is the fixed key suffix from RFC 6455 section 1.3.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+)
+
+// tickEvent is broadcast to WebSocket clients once a second.
+type tickEvent struct {
+	Task      string `json:"task"`
+	Remaining string `json:"remaining"`
+	Status    string `json:"status"`
+}
+
+// hub fans messages out to every connected WebSocket client.
+type hub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+func newHub() *hub {
+	return &hub{clients: make(map[chan []byte]struct{})}
+}
+
+func (h *hub) subscribe() chan []byte {
+	ch := make(chan []byte, 8)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *hub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+// broadcast never blocks: a client too slow to keep up misses messages.
+func (h *hub) broadcast(msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+func (h *hub) empty() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients) == 0
+}
+
+// broadcastTicks sends the timer's state to the hub every second.
+func broadcastTicks(t *Timer, h *hub) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if h.empty() {
+			continue
+		}
+
+		event := tickEvent{Status: "idle"}
+		if task, ok := t.Current(); ok {
+			event.Task = task.Name
+			event.Remaining = task.Remaining.Round(time.Second).String()
+			event.Status = "running"
+			if t.Paused() {
+				event.Status = "paused"
+			}
+		}
+		msg, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		h.broadcast(msg)
+	}
+}
+
+// websocket upgrades the request and streams tick events until the client
+// goes away. Only what this one-way feed needs of RFC 6455 is implemented:
+// the handshake, unmasked server text frames, and reading client frames
+// to notice a close.
+func (s *server) websocket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	ch := s.hub.subscribe()
+	defer s.hub.unsubscribe(ch)
+
+	closed := make(chan struct{})
+	go func() {
+		readFrames(rw.Reader)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case msg := <-ch:
+			if err := writeFrame(conn, opText, msg); err != nil {
+				return
+			}
+		case <-closed:
+			writeFrame(conn, opClose, nil)
+			return
+		}
+	}
+}
+
+// readFrames discards client frames until a close frame or a read error.
+func readFrames(r *bufio.Reader) {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return
+		}
+		opcode := header[0] & 0x0f
+		masked := header[1]&0x80 != 0
+		length := uint64(header[1] & 0x7f)
+
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		if masked {
+			length += 4
+		}
+
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return
+		}
+		if opcode == opClose {
+			return
+		}
+	}
+}
+
+func writeFrame(conn net.Conn, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := conn.Write(append(header, payload...))
+	return err
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document with its indentation measured
+// and any comment removed.
+type yamlLine struct {
+	n      int
+	indent int
+	text   string
+}
+
+// parseYAML reads the subset of YAML task files need: nested block
+// mappings and lists, and flow lists and mappings such as [a, b] and
+// {name: Study, duration: 25m}, of plain or quoted scalars. Anchors, tags,
+// multi-line scalars and multiple documents are not supported.
+func parseYAML(r io.Reader) (*docNode, error) {
+	var lines []yamlLine
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		raw := strings.TrimRight(stripYAMLComment(scanner.Text()), " \t")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, &lineError{n, "tabs are not allowed for indentation"}
+		}
+		lines = append(lines, yamlLine{n: n, indent: len(raw) - len(text), text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return &docNode{Kind: docMap, Line: 1, Values: map[string]*docNode{}}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	node, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(lines) {
+		return nil, &lineError{lines[p.i].n, "unexpected indentation"}
+	}
+	return node, nil
+}
+
+// stripYAMLComment removes a # comment that is outside quotes and starts
+// the line or follows a space.
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+// block parses the list or mapping whose lines start at indent.
+func (p *yamlParser) block(indent int) (*docNode, error) {
+	first := p.lines[p.i]
+	switch {
+	case first.text == "-" || strings.HasPrefix(first.text, "- "):
+		return p.list(indent)
+	case strings.HasPrefix(first.text, "[") || strings.HasPrefix(first.text, "{"):
+		p.i++
+		return parseYAMLFlow(first.text, first.n)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) list(indent int) (*docNode, error) {
+	node := &docNode{Kind: docList, Line: p.lines[p.i].n}
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent != indent || (line.text != "-" && !strings.HasPrefix(line.text, "- ")) {
+			break
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		var item *docNode
+		var err error
+		switch {
+		case rest == "":
+			p.i++
+			item, err = p.nested(indent, line.n)
+		case isYAMLKey(rest):
+			// "- key: value" starts a mapping whose other keys line up
+			// with the first.
+			p.lines[p.i] = yamlLine{n: line.n, indent: indent + len(line.text) - len(rest), text: rest}
+			item, err = p.mapping(p.lines[p.i].indent)
+		default:
+			p.i++
+			item, err = parseYAMLFlow(rest, line.n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Items = append(node.Items, item)
+	}
+	return node, nil
+}
+
+func (p *yamlParser) mapping(indent int) (*docNode, error) {
+	node := &docNode{Kind: docMap, Line: p.lines[p.i].n, Values: map[string]*docNode{}}
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent != indent || line.text == "-" || strings.HasPrefix(line.text, "- ") {
+			break
+		}
+		if !isYAMLKey(line.text) {
+			return nil, &lineError{line.n, fmt.Sprintf("expected \"key: value\", got %q", line.text)}
+		}
+
+		key, value := splitYAMLKey(line.text)
+		if _, dup := node.Values[key]; dup {
+			return nil, &lineError{line.n, fmt.Sprintf("duplicate key %q", key)}
+		}
+		p.i++
+		var child *docNode
+		var err error
+		if value == "" {
+			child, err = p.nested(indent, line.n)
+		} else {
+			child, err = parseYAMLFlow(value, line.n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Keys = append(node.Keys, key)
+		node.Values[key] = child
+	}
+	return node, nil
+}
+
+// nested parses the block under a key or dash at indent, which is empty
+// unless the next line is indented further. A list may also sit at the
+// same indentation as its key.
+func (p *yamlParser) nested(indent, n int) (*docNode, error) {
+	if p.i < len(p.lines) {
+		next := p.lines[p.i]
+		isItem := next.text == "-" || strings.HasPrefix(next.text, "- ")
+		if next.indent > indent || (next.indent == indent && isItem && p.i > 0 && !strings.HasPrefix(p.lines[p.i-1].text, "-")) {
+			return p.block(next.indent)
+		}
+	}
+	return &docNode{Kind: docScalar, Line: n}, nil
+}
+
+// isYAMLKey reports whether text starts with a key followed by a colon.
+func isYAMLKey(text string) bool {
+	if text == "" || text[0] == '[' || text[0] == '{' || text[0] == '"' || text[0] == '\'' {
+		return false
+	}
+	i := strings.Index(text, ":")
+	return i > 0 && (i == len(text)-1 || text[i+1] == ' ')
+}
+
+func splitYAMLKey(text string) (string, string) {
+	key, value, _ := strings.Cut(text, ":")
+	return strings.TrimSpace(key), strings.TrimSpace(value)
+}
+
+// parseYAMLFlow parses a scalar or a flow list or mapping on line n.
+func parseYAMLFlow(s string, n int) (*docNode, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, &lineError{n, "unterminated list"}
+		}
+		parts, err := splitYAMLFlow(s[1:len(s)-1], n)
+		if err != nil {
+			return nil, err
+		}
+		node := &docNode{Kind: docList, Line: n}
+		for _, part := range parts {
+			item, err := parseYAMLFlow(part, n)
+			if err != nil {
+				return nil, err
+			}
+			node.Items = append(node.Items, item)
+		}
+		return node, nil
+
+	case strings.HasPrefix(s, "{"):
+		if !strings.HasSuffix(s, "}") {
+			return nil, &lineError{n, "unterminated mapping"}
+		}
+		parts, err := splitYAMLFlow(s[1:len(s)-1], n)
+		if err != nil {
+			return nil, err
+		}
+		node := &docNode{Kind: docMap, Line: n, Values: map[string]*docNode{}}
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if !isYAMLKey(part) {
+				return nil, &lineError{n, fmt.Sprintf("expected \"key: value\", got %q", part)}
+			}
+			key, value := splitYAMLKey(part)
+			if _, dup := node.Values[key]; dup {
+				return nil, &lineError{n, fmt.Sprintf("duplicate key %q", key)}
+			}
+			child, err := parseYAMLFlow(value, n)
+			if err != nil {
+				return nil, err
+			}
+			node.Keys = append(node.Keys, key)
+			node.Values[key] = child
+		}
+		return node, nil
+	}
+
+	value, err := unquoteYAML(s)
+	if err != nil {
+		return nil, &lineError{n, err.Error()}
+	}
+	return &docNode{Kind: docScalar, Line: n, Value: value}, nil
+}
+
+// splitYAMLFlow splits the inside of a flow collection at the commas that
+// are not nested or quoted.
+func splitYAMLFlow(s string, n int) ([]string, error) {
+	var parts []string
+	var quote rune
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, &lineError{n, "unbalanced brackets or quotes"}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, s[start:])
+	}
+	return parts, nil
+}
+
+func unquoteYAML(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s == "~" || s == "null":
+		return "", nil
+	}
+	return s, nil
+}